@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"strconv"
+	"path/filepath"
 	"strings"
 
 	"fyne.io/fyne/v2"
@@ -14,6 +16,7 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 	apppkg "github.com/Fepozopo/bsc-faire/internal/app"
+	"github.com/Fepozopo/bsc-faire/internal/cli"
 	"github.com/Fepozopo/bsc-faire/internal/version"
 )
 
@@ -30,6 +33,17 @@ func RunGUI() {
 	mockFailsEntry.SetPlaceHolder("Mock fail indices (e.g. 1,3,5)")
 	mockFailsEntry.Disable() // Start disabled
 
+	// Shipments for canceled, delivered, or cancel-requested orders are blocked unless the user chooses otherwise.
+	orderStateSelect := widget.NewSelect([]string{"Off", "Warn", "Block"}, nil)
+	orderStateSelect.SetSelected("Block")
+	orderStateCheck := func() apppkg.OrderStateCheck {
+		check, err := apppkg.ParseOrderStateCheck(orderStateSelect.Selected)
+		if err != nil {
+			return apppkg.OrderStateCheckBlock
+		}
+		return check
+	}
+
 	mockCheck := widget.NewCheck("Use Mock Server", func(checked bool) {
 		useMock = checked
 		if checked {
@@ -49,108 +63,118 @@ func RunGUI() {
 			if filePath == "" {
 				return
 			}
-			if len(filePath) < 4 || filePath[len(filePath)-4:] != ".csv" {
-				dialog.ShowError(fmt.Errorf("please select a .csv file"), w)
+			if ext := strings.ToLower(filepath.Ext(filePath)); ext != ".csv" && ext != ".xlsx" {
+				dialog.ShowError(fmt.Errorf("please select a .csv or .xlsx file"), w)
 				return
 			}
 
-			fileLabel := widget.NewLabel(fmt.Sprintf("Selected file: %s", filePath))
-			var confirmDialog dialog.Dialog
-
-			submitBtn := widget.NewButton("Submit", nil)
+			profile, err := apppkg.ShipmentCSVProfileFromEnv()
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			// Large exports take a moment to parse, so the preview is built off the UI thread.
+			progress := widget.NewProgressBarInfinite()
+			progressDialog := dialog.NewCustom("Reading Shipments", "Cancel",
+				container.NewVBox(widget.NewLabel(fmt.Sprintf("Reading %s...", filepath.Base(filePath))), progress), w)
+			readCtx, cancelRead := context.WithCancel(context.Background())
+			progressDialog.SetOnClosed(cancelRead)
+			progressDialog.Show()
 
-			content := container.NewVBox(
-				fileLabel,
-				submitBtn,
-			)
-			confirmDialog = dialog.NewCustom("Confirm File", "Cancel", content, w)
-			confirmDialog.Show()
+			go func() {
+				preview, err := apppkg.PreviewShipments(readCtx, filePath, nil, apppkg.ShipmentPreviewOptions{Profile: &profile})
+				fyne.Do(func() {
+					if readCtx.Err() != nil {
+						return // The user canceled while the file was being read.
+					}
+					progressDialog.Hide()
+					if err != nil {
+						dialog.ShowError(fmt.Errorf("failed to read shipments: %w", err), w)
+						return
+					}
 
-			submitBtn.OnTapped = func() {
-				confirmDialog.Hide()
+					fileLabel := widget.NewLabel(fmt.Sprintf("Selected file: %s", filePath))
+					var confirmDialog dialog.Dialog
+					// Closing the dialog abandons any order verification still in progress.
+					verifyCtx, cancelVerify := context.WithCancel(context.Background())
 
-				progress := widget.NewProgressBarInfinite()
-				progressLabel := widget.NewLabel("Processing shipments...")
-				progressDialog := dialog.NewCustom("Processing", "Cancel", container.NewVBox(progressLabel, progress), w)
-				progressDialog.Show()
+					previewEntry := widget.NewMultiLineEntry()
+					previewEntry.SetText(apppkg.FormatShipmentPreview(preview))
+					previewScroll := container.NewVScroll(previewEntry)
+					previewScroll.SetMinSize(fyne.NewSize(600, 300))
 
-				go func() {
-					var client apppkg.FaireClientInterface
-					if useMock {
-						failMap := map[int]bool{}
-						mockFails := mockFailsEntry.Text
-						if mockFails != "" {
-							for _, s := range strings.Split(mockFails, ",") {
-								if idx, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
-									failMap[idx] = true
-								}
-							}
+					verifyBtn := widget.NewButton("Verify Orders with Faire", nil)
+					verifyBtn.OnTapped = func() {
+						client, err := cli.NewFaireClient(useMock, "")
+						if err != nil {
+							dialog.ShowError(err, w)
+							return
 						}
-						client = &apppkg.MockFaireClient{FailOnCall: failMap}
-					} else {
-						client = apppkg.NewFaireClient()
+						verifyBtn.Disable()
+						previewEntry.SetText("Checking orders with Faire...")
+						check := orderStateCheck()
+						go func() {
+							verified, err := apppkg.PreviewShipments(verifyCtx, filePath, client, apppkg.ShipmentPreviewOptions{
+								VerifyOrders:    true,
+								OrderStateCheck: check,
+								Profile:         &profile,
+							})
+							fyne.Do(func() {
+								verifyBtn.Enable()
+								if err != nil {
+									previewEntry.SetText(fmt.Sprintf("Order verification failed: %v", err))
+									return
+								}
+								previewEntry.SetText(apppkg.FormatShipmentPreview(verified))
+							})
+						}()
 					}
-					processed, failed, err := apppkg.ProcessShipments(filePath, client)
+					submitBtn := widget.NewButton("Submit", nil)
 
-					fyne.Do(func() {
-						var formatPayloads = func(payloads []apppkg.ShipmentPayload, showError bool) string {
-							if len(payloads) == 0 {
-								return "  None"
-							}
-							msg := ""
-							for _, p := range payloads {
-								msg += fmt.Sprintf(
-									"  OrderID: %s\n    SaleSource: %s\n    Carrier: %s\n    TrackingCode: %s\n",
-									apppkg.OrderIDToDisplayID(p.OrderID), p.SaleSource, p.Carrier, p.TrackingCode,
-								)
-								if showError && p.ErrorMsg != "" {
-									msg += fmt.Sprintf("    ShippingCost: $%.2f\n    Error: %s\n\n", float32(p.MakerCostCents)/100, p.ErrorMsg)
-								} else {
-									msg += fmt.Sprintf("    ShippingCost: $%.2f\n\n", float32(p.MakerCostCents)/100)
-								}
-							}
-							return msg
-						}
+					content := container.NewVBox(
+						fileLabel,
+						previewScroll,
+						container.NewHBox(verifyBtn, layout.NewSpacer(), submitBtn),
+					)
+					confirmDialog = dialog.NewCustom("Confirm File", "Cancel", content, w)
+					confirmDialog.SetOnClosed(cancelVerify)
+					confirmDialog.Show()
 
-						total := len(processed) + len(failed)
-						successful := len(processed)
-						failedCount := len(failed)
+					submitBtn.OnTapped = func() {
+						confirmDialog.Hide()
 
-						var msg string
-						if err != nil {
-							msg = fmt.Sprintf("Processed %d shipments: %d successful, %d failed\n\nFailed to process shipments: %v", total, successful, failedCount, err)
-						} else {
-							summary := fmt.Sprintf("Processed %d shipments: %d successful, %d failed\n\n", total, successful, failedCount)
-							msg = summary
-							msg += "Failed Shipments:\n"
-							msg += formatPayloads(failed, true)
-							msg += "\n\nProcessed Shipments:\n"
-							msg += formatPayloads(processed, false)
-						}
+						shipmentSubmission{parent: w, useMock: useMock, mockFails: mockFailsEntry.Text, orderStateCheck: orderStateCheck()}.run(filePath,
+							func(ctx context.Context, client apppkg.FaireClientInterface, options apppkg.ShipmentProcessOptions) (apppkg.ShipmentResults, error) {
+								options.Profile = &profile
+								return apppkg.ProcessShipmentsWithOptions(ctx, filePath, client, options)
+							})
+					}
+				})
+			}()
+		})
+	})
 
-						fyne.CurrentApp().SendNotification(&fyne.Notification{
-							Title: func() string {
-								if err != nil {
-									return "Error"
-								} else {
-									return "Success"
-								}
-							}(),
-							Content: msg,
-						})
-						progressDialog.Hide()
-						if err != nil {
-							dialog.ShowError(fmt.Errorf("%s", msg), w)
-						} else {
-							entry := widget.NewMultiLineEntry()
-							entry.SetText(msg)
-							scroll := container.NewVScroll(entry)
-							scroll.SetMinSize(fyne.NewSize(380, 250))
-							dialog.ShowCustom("Success", "OK", scroll, w)
-						}
-					})
-				}()
+	// Button: Retry Saved Shipments
+	retrySavedBtn := widget.NewButton("Retry Saved Shipments", func() {
+		openFileWindow(w, func(filePath string, e error) {
+			if e != nil {
+				dialog.ShowError(e, w)
+				return
 			}
+			if filePath == "" {
+				return
+			}
+			payloads, err := apppkg.LoadShipmentPayloads(filePath)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			submission := shipmentSubmission{parent: w, useMock: useMock, mockFails: mockFailsEntry.Text, orderStateCheck: orderStateCheck()}
+			dialog.ShowConfirm("Retry Saved Shipments", fmt.Sprintf("Resubmit %d saved shipments from %s?", len(payloads), filePath), func(ok bool) {
+				if ok {
+					submission.retry(filePath, payloads)
+				}
+			}, w)
 		})
 	})
 
@@ -171,42 +195,28 @@ func RunGUI() {
 					dialog.ShowError(fmt.Errorf("invalid or missing token for sale source '%s'", saleSource), w)
 					return
 				}
-				progress := widget.NewProgressBarInfinite()
-				progressLabel := widget.NewLabel("Fetching orders...")
-				progressDialog := dialog.NewCustom("Fetching Orders", "Cancel", container.NewVBox(progressLabel, progress), w)
+				client, err := cli.NewFaireClient(useMock, "")
+				if err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				progress := newOrderProgressView("Fetching orders...")
+				progressDialog := dialog.NewCustom("Fetching Orders", "Cancel", progress.content, w)
+				ctx, cancel := context.WithCancel(context.Background())
+				progressDialog.SetOnClosed(cancel)
 				progressDialog.Show()
 
 				go func() {
-					var client apppkg.FaireClientInterface
-					if useMock {
-						client = &apppkg.MockFaireClient{Orders: apppkg.MockOrders}
-					} else {
-						client = apppkg.NewFaireClient()
+					allOrders, err := apppkg.GetActiveOrders(ctx, client, token, progress.update)
+					if errors.Is(err, context.Canceled) {
+						return
 					}
-					var allOrders []apppkg.Order
-					currPage := 1
-					for {
-						resp, err := client.GetAllOrders(token, 50, currPage, "DELIVERED,BACKORDERED,CANCELED,PRE_TRANSIT,IN_TRANSIT,RETURNED,PENDING_RETAILER_CONFIRMATION,DAMAGED_OR_MISSING")
-						if err != nil {
-							fyne.Do(func() {
-								progressDialog.Hide()
-								dialog.ShowError(fmt.Errorf("failed to get orders: %v", err), w)
-							})
-							return
-						}
-						var ordersResp apppkg.Orders
-						if err := json.Unmarshal(resp, &ordersResp); err != nil {
-							fyne.Do(func() {
-								progressDialog.Hide()
-								dialog.ShowError(fmt.Errorf("failed to parse orders: %v", err), w)
-							})
-							return
-						}
-						allOrders = append(allOrders, ordersResp.Orders...)
-						if len(ordersResp.Orders) < 50 {
-							break
-						}
-						currPage++
+					if err != nil {
+						fyne.Do(func() {
+							progressDialog.Hide()
+							dialog.ShowError(fmt.Errorf("failed to get orders: %v", err), w)
+						})
+						return
 					}
 					fyne.Do(func() {
 						progressDialog.Hide()
@@ -265,19 +275,23 @@ func RunGUI() {
 					dialog.ShowError(fmt.Errorf("invalid or missing token for sale source '%s'", saleSource), w)
 					return
 				}
+				client, err := cli.NewFaireClient(useMock, "")
+				if err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
 				progress := widget.NewProgressBarInfinite()
 				progressLabel := widget.NewLabel("Fetching order...")
 				progressDialog := dialog.NewCustom("Fetching Order", "Cancel", container.NewVBox(progressLabel, progress), w)
+				ctx, cancel := context.WithCancel(context.Background())
+				progressDialog.SetOnClosed(cancel)
 				progressDialog.Show()
 
 				go func() {
-					var client apppkg.FaireClientInterface
-					if useMock {
-						client = &apppkg.MockFaireClient{Orders: apppkg.MockOrders}
-					} else {
-						client = apppkg.NewFaireClient()
+					resp, err := client.GetOrderByID(ctx, orderID, token)
+					if errors.Is(err, context.Canceled) {
+						return
 					}
-					resp, err := client.GetOrderByID(orderID, token)
 					fyne.Do(func() {
 						progressDialog.Hide()
 						if err != nil {
@@ -300,6 +314,12 @@ func RunGUI() {
 			}, w)
 	})
 
+	// Button: Shipment History
+	historyBtn := newShipmentHistoryButton(w)
+
+	// Button: Logs
+	logsBtn := newLogsButton(w)
+
 	// Button: Self-Update
 	updateBtn := widget.NewButton("Check for Updates", func() {
 		checkForUpdates(w, true)
@@ -315,13 +335,21 @@ func RunGUI() {
 			widget.NewLabel(""),
 			container.NewGridWrap(fyne.NewSize(250, mockFailsEntry.MinSize().Height), mockFailsEntry),
 		),
+		container.NewHBox(
+			layout.NewSpacer(),
+			widget.NewLabel("Orders that should not be shipped:"),
+			orderStateSelect,
+		),
 		processBtn,
+		retrySavedBtn,
 		exportNewBtn,
 		exportSelectedBtn,
 		exportBackorderedBtn,
 		widget.NewLabel(""),
 		ordersBtn,
 		orderBtn,
+		historyBtn,
+		logsBtn,
 		widget.NewLabel(""),
 		layout.NewSpacer(),
 		updateBtn,
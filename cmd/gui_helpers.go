@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 	apppkg "github.com/Fepozopo/bsc-faire/internal/app"
+	"github.com/Fepozopo/bsc-faire/internal/cli"
 	"github.com/Fepozopo/bsc-faire/internal/version"
 	"github.com/blang/semver"
 	"github.com/rhysd/go-github-selfupdate/selfupdate"
@@ -124,7 +129,7 @@ func newOrderExportButton(parent fyne.Window, useMock func() bool, configuration
 		}
 		orderIDsEntry := widget.NewMultiLineEntry()
 		if configuration.UsesOrderIDs {
-			orderIDsEntry.SetPlaceHolder("One display ID or bo_ ID per line; commas and semicolons also work")
+			orderIDsEntry.SetPlaceHolder("One display ID or bo_ ID per line; commas, semicolons, and spaces also work")
 			orderIDsEntry.SetMinRowsVisible(4)
 			formItems = append(formItems, widget.NewFormItem("Order IDs", orderIDsEntry))
 		}
@@ -157,22 +162,25 @@ func newOrderExportButton(parent fyne.Window, useMock func() bool, configuration
 				return
 			}
 
-			progress := widget.NewProgressBarInfinite()
-			progressLabel := widget.NewLabel(configuration.ProgressMessage)
-			progressDialog := dialog.NewCustom("Exporting", "Cancel", container.NewVBox(progressLabel, progress), parent)
+			client, err := cli.NewFaireClient(useMock(), "")
+			if err != nil {
+				dialog.ShowError(err, parent)
+				return
+			}
+			progress := newOrderProgressView(configuration.ProgressMessage)
+			progressDialog := dialog.NewCustom("Exporting", "Cancel", progress.content, parent)
+			ctx, cancel := context.WithCancel(context.Background())
+			progressDialog.SetOnClosed(cancel)
 			progressDialog.Show()
 
 			go func() {
-				var client apppkg.OrderClient
-				if useMock() {
-					client = &apppkg.MockFaireClient{Orders: apppkg.MockOrders}
-				} else {
-					client = apppkg.NewFaireClient()
-				}
-
-				count, err := apppkg.ExportOrdersToCSV(client, apiToken, saleSource, outputPath, filter)
+				count, err := apppkg.ExportOrdersToCSV(ctx, client, apiToken, saleSource, outputPath, filter, progress.update)
 				fyne.Do(func() {
 					progressDialog.Hide()
+					if errors.Is(err, context.Canceled) {
+						dialog.ShowInformation("Export Canceled", "The export was canceled; no file was written.", parent)
+						return
+					}
 					if err != nil {
 						dialog.ShowError(fmt.Errorf("export failed: %w", err), parent)
 						return
@@ -183,3 +191,300 @@ func newOrderExportButton(parent fyne.Window, useMock func() bool, configuration
 		}, parent)
 	})
 }
+
+// orderProgressView shows the progress of an order retrieval. Lookups by identifier fill a progress bar; listings by
+// state show a moving bar and a page count, because Faire does not report the number of pages in advance.
+type orderProgressView struct {
+	label   *widget.Label
+	pending *widget.ProgressBarInfinite
+	bar     *widget.ProgressBar
+	content *fyne.Container
+}
+
+// newOrderProgressView creates a view that shows message until the first progress report.
+func newOrderProgressView(message string) *orderProgressView {
+	view := &orderProgressView{
+		label:   widget.NewLabel(message),
+		pending: widget.NewProgressBarInfinite(),
+		bar:     widget.NewProgressBar(),
+	}
+	view.bar.Hide()
+	view.content = container.NewVBox(view.label, view.pending, view.bar)
+	return view
+}
+
+// update shows p; it may be called from any goroutine.
+func (v *orderProgressView) update(p apppkg.OrderProgress) {
+	fyne.Do(func() {
+		v.label.SetText(apppkg.FormatOrderProgress(p))
+		if p.Total > 0 {
+			v.pending.Stop()
+			v.pending.Hide()
+			v.bar.Show()
+			v.bar.SetValue(float64(p.Done) / float64(p.Total))
+		}
+	})
+}
+
+// newShipmentHistoryButton creates a button that browses recorded shipment runs, filtered by order or tracking code.
+func newShipmentHistoryButton(parent fyne.Window) *widget.Button {
+	return widget.NewButton("Shipment History", func() {
+		store, err := apppkg.DefaultShipmentHistory()
+		if err != nil {
+			dialog.ShowError(err, parent)
+			return
+		}
+		runs, skipped, err := store.Runs()
+		if err != nil {
+			dialog.ShowError(err, parent)
+			return
+		}
+		footer := store.Path
+		if skipped > 0 {
+			footer = fmt.Sprintf("%s (skipped %d unreadable lines)", store.Path, skipped)
+		}
+
+		results := widget.NewMultiLineEntry()
+		results.SetText(apppkg.FormatShipmentRuns(runs))
+		searchEntry := widget.NewEntry()
+		searchEntry.SetPlaceHolder("Filter by order display ID or tracking code")
+		searchEntry.OnChanged = func(query string) {
+			results.SetText(apppkg.FormatShipmentRuns(apppkg.SearchShipmentRuns(runs, query)))
+		}
+
+		scroll := container.NewVScroll(results)
+		scroll.SetMinSize(fyne.NewSize(700, 400))
+		content := container.NewBorder(searchEntry, widget.NewLabel(footer), nil, nil, scroll)
+		dialog.ShowCustom("Shipment History", "Close", content, parent)
+	})
+}
+
+// logViewBytes is how much of the end of the request log the Logs dialog shows.
+const logViewBytes = 256 << 10
+
+// newLogsButton creates a button that shows the end of the Faire request log, including the raw responses to
+// failed requests that Faire support asks for.
+func newLogsButton(parent fyne.Window) *widget.Button {
+	return widget.NewButton("Logs", func() {
+		config, err := apppkg.LogConfigFromEnv()
+		if err != nil {
+			dialog.ShowError(err, parent)
+			return
+		}
+
+		logText := widget.NewMultiLineEntry()
+		logText.Wrapping = fyne.TextWrapOff
+		scroll := container.NewVScroll(logText)
+		scroll.SetMinSize(fyne.NewSize(800, 450))
+		refresh := func() {
+			text, err := apppkg.ReadLogTail(config.Path, logViewBytes)
+			switch {
+			case err != nil:
+				text = err.Error()
+			case text == "":
+				text = "No requests have been logged yet."
+			}
+			if config.Disabled {
+				text = "Logging is off. Set FAIRE_LOG_LEVEL to info, or to debug for headers and bodies, to record requests.\n\n" + text
+			}
+			logText.SetText(text)
+			scroll.ScrollToBottom()
+		}
+		refresh()
+
+		copyBtn := widget.NewButton("Copy", func() {
+			fyne.CurrentApp().Clipboard().SetContent(logText.Text)
+		})
+		refreshBtn := widget.NewButton("Refresh", refresh)
+		footer := container.NewHBox(widget.NewLabel(config.Path), layout.NewSpacer(), copyBtn, refreshBtn)
+		dialog.ShowCustom("Logs", "Close", container.NewBorder(nil, footer, nil, nil, scroll), parent)
+	})
+}
+
+// shipmentSubmission posts shipments from the GUI with the client mode selected when it was created.
+type shipmentSubmission struct {
+	parent          fyne.Window
+	useMock         bool
+	mockFails       string
+	orderStateCheck apppkg.OrderStateCheck
+}
+
+// run shows progress while submit posts shipments, records the run in the shipment history, and shows the results.
+// inputPath identifies the run's source file in the history. The dialog's Cancel button stops further shipments from
+// being sent, and the results then show what was completed. A log below the progress bar lists each shipment as it completes.
+func (s shipmentSubmission) run(inputPath string, submit func(ctx context.Context, client apppkg.FaireClientInterface, options apppkg.ShipmentProcessOptions) (apppkg.ShipmentResults, error)) {
+	client, err := cli.NewFaireClient(s.useMock, s.mockFails)
+	if err != nil {
+		dialog.ShowError(err, s.parent)
+		return
+	}
+	progress := widget.NewProgressBar()
+	progressLabel := widget.NewLabel("Processing shipments...")
+	progressLog := widget.NewMultiLineEntry()
+	progressLog.Wrapping = fyne.TextWrapOff
+	logScroll := container.NewVScroll(progressLog)
+	logScroll.SetMinSize(fyne.NewSize(600, 200))
+	progressDialog := dialog.NewCustom("Processing", "Cancel", container.NewBorder(container.NewVBox(progressLabel, progress), nil, nil, nil, logScroll), s.parent)
+	ctx, cancel := context.WithCancel(context.Background())
+	progressDialog.SetOnClosed(cancel)
+	progressDialog.Show()
+
+	go func() {
+		startedAt := time.Now()
+		results, err := submit(ctx, client, apppkg.ShipmentProcessOptions{
+			Workers:               cli.DefaultShipmentWorkers,
+			SkipExistingShipments: true,
+			OrderStateCheck:       s.orderStateCheck,
+			OnProgress: func(p apppkg.ShipmentProgress) {
+				fyne.Do(func() {
+					if p.Total > 0 {
+						progress.SetValue(float64(p.Done) / float64(p.Total))
+					}
+					progressLabel.SetText(fmt.Sprintf("Processed %d of %d shipments: %d succeeded, %d failed, %d already present, %d duplicates",
+						p.Done, p.Total, p.Succeeded, p.Failed, p.AlreadyPresent, p.Duplicates))
+					if p.Done > 0 {
+						progressLog.Append(formatProgressLogLine(p) + "\n")
+						logScroll.ScrollToBottom()
+					}
+				})
+			},
+		})
+		historyErr := cli.RecordShipmentRun(apppkg.NewShipmentRun(inputPath, s.useMock, startedAt, results, err))
+
+		fyne.Do(func() {
+			progressDialog.Hide()
+			s.showResults(inputPath, results, err, historyErr)
+		})
+	}()
+}
+
+// formatProgressLogLine describes the shipment that just completed in p for the live progress log.
+func formatProgressLogLine(p apppkg.ShipmentProgress) string {
+	line := fmt.Sprintf("[%d/%d] %s %s %s %s", p.Done, p.Total, p.Outcome,
+		apppkg.OrderIDToDisplayID(p.Payload.OrderID), p.Payload.Carrier, p.Payload.TrackingCode)
+	if p.Payload.ErrorMsg != "" {
+		line += ": " + p.Payload.ErrorMsg
+	}
+	return line
+}
+
+// retry resubmits payloads saved from an earlier run; inputPath identifies them in the history.
+func (s shipmentSubmission) retry(inputPath string, payloads []apppkg.ShipmentPayload) {
+	s.run(inputPath, func(ctx context.Context, client apppkg.FaireClientInterface, options apppkg.ShipmentProcessOptions) (apppkg.ShipmentResults, error) {
+		return apppkg.RetryShipments(ctx, payloads, client, options), nil
+	})
+}
+
+// showResults notifies the user of a finished run and offers to save or retry its failed shipments.
+func (s shipmentSubmission) showResults(inputPath string, results apppkg.ShipmentResults, err, historyErr error) {
+	var formatPayloads = func(payloads []apppkg.ShipmentPayload, showError bool) string {
+		if len(payloads) == 0 {
+			return "  None"
+		}
+		msg := ""
+		for _, p := range payloads {
+			msg += fmt.Sprintf(
+				"  OrderID: %s\n    SaleSource: %s\n    Carrier: %s\n    TrackingCode: %s\n",
+				apppkg.OrderIDToDisplayID(p.OrderID), p.SaleSource, p.Carrier, p.TrackingCode,
+			)
+			if p.Created != nil && p.Created.ID != "" {
+				msg += fmt.Sprintf("    FaireShipmentID: %s\n", p.Created.ID)
+			}
+			if showError && p.ErrorMsg != "" {
+				msg += fmt.Sprintf("    ShippingCost: $%.2f\n    Error: %s\n\n", float32(p.MakerCostCents)/100, p.ErrorMsg)
+			} else {
+				msg += fmt.Sprintf("    ShippingCost: $%.2f\n\n", float32(p.MakerCostCents)/100)
+			}
+		}
+		return msg
+	}
+
+	var msg string
+	if err != nil {
+		msg = fmt.Sprintf("%s\n\nFailed to process shipments: %v", cli.FormatShipmentSummary(results), err)
+	} else {
+		msg = cli.FormatShipmentSummary(results) + "\n\n"
+		msg += "Failed Shipments:\n"
+		if len(results.Failed) == 0 {
+			msg += formatPayloads(nil, true)
+		}
+		for _, group := range apppkg.GroupFailures(results.Failed) {
+			msg += fmt.Sprintf("\n%s (%d)\n  What to do: %s\n\n", group.Kind.Title(), len(group.Payloads), group.Kind.Advice())
+			msg += formatPayloads(group.Payloads, true)
+		}
+		msg += "\n\nSkipped Rows (not sent to Faire):\n"
+		msg += apppkg.FormatSkippedRows(results.SkippedRows)
+		if len(results.Warnings) > 0 {
+			msg += "\n\nWarnings (sent to Faire, please check):\n"
+			msg += apppkg.FormatShipmentWarnings(results.Warnings)
+		}
+		msg += "\n\nAlready Present (not posted again):\n"
+		msg += formatPayloads(results.AlreadyPresent, false)
+		if len(results.Duplicates) > 0 {
+			msg += "\n\nDuplicates in the file (not posted again):\n"
+			msg += formatPayloads(results.Duplicates, true)
+		}
+		msg += "\n\nProcessed Shipments:\n"
+		msg += formatPayloads(results.Processed, false)
+	}
+	if historyErr != nil {
+		msg += fmt.Sprintf("\n\nWarning: this run was not saved to the shipment history: %v", historyErr)
+	}
+
+	fyne.CurrentApp().SendNotification(&fyne.Notification{
+		Title: func() string {
+			if err != nil {
+				return "Error"
+			} else {
+				return "Success"
+			}
+		}(),
+		Content: msg,
+	})
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("%s", msg), s.parent)
+		return
+	}
+
+	entry := widget.NewMultiLineEntry()
+	entry.SetText(msg)
+	scroll := container.NewVScroll(entry)
+	scroll.SetMinSize(fyne.NewSize(380, 250))
+
+	exportBtn := widget.NewButton("Export Results", func() {
+		outputPath, err := apppkg.DownloadsFilePath(apppkg.ShipmentResultsFilename(inputPath))
+		if err == nil {
+			err = apppkg.WriteShipmentResultsCSV(outputPath, results)
+		}
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("export results: %w", err), s.parent)
+			return
+		}
+		dialog.ShowInformation("Results Exported", fmt.Sprintf("Saved shipment results to %s", outputPath), s.parent)
+	})
+	if len(results.Failed) == 0 {
+		dialog.ShowCustom("Success", "OK", container.NewBorder(nil, container.NewHBox(exportBtn), nil, nil, scroll), s.parent)
+		return
+	}
+
+	failed := results.Failed
+	var resultsDialog dialog.Dialog
+	saveBtn := widget.NewButton("Save Failed Shipments", func() {
+		outputPath, err := apppkg.DownloadsFilePath("faire_failed_shipments.json")
+		if err == nil {
+			err = apppkg.SaveShipmentPayloads(outputPath, failed)
+		}
+		if err != nil {
+			dialog.ShowError(err, s.parent)
+			return
+		}
+		dialog.ShowInformation("Failed Shipments Saved", fmt.Sprintf("Saved %d failed shipments to %s", len(failed), outputPath), s.parent)
+	})
+	retryBtn := widget.NewButton("Retry Failed", func() {
+		resultsDialog.Hide()
+		s.retry(inputPath, failed)
+	})
+	content := container.NewBorder(nil, container.NewHBox(exportBtn, saveBtn, layout.NewSpacer(), retryBtn), nil, nil, scroll)
+	resultsDialog = dialog.NewCustom("Finished with Failures", "OK", content, s.parent)
+	resultsDialog.Show()
+}
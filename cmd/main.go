@@ -1,6 +1,15 @@
 package main
 
-// main starts the graphical Faire application.
+import (
+	"os"
+
+	"github.com/Fepozopo/bsc-faire/internal/cli"
+)
+
+// main runs a command-line subcommand when arguments are supplied and otherwise starts the graphical Faire application.
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+	}
 	RunGUI()
 }
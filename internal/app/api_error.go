@@ -0,0 +1,81 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// APIError reports a non-successful response from the Faire API. FaireClient methods return it, possibly wrapped,
+// so callers can inspect it with errors.As.
+type APIError struct {
+	// Method and Path identify the request; Path excludes the query string.
+	Method string
+	Path   string
+	// StatusCode and Status are the HTTP status, such as 404 and "404 Not Found".
+	StatusCode int
+	Status     string
+	// Code and Message are Faire's error type and description, when the response body is a JSON error.
+	Code    string
+	Message string
+	// Body is the trimmed response body.
+	Body string
+	// Retryable reports whether repeating the request may succeed and is safe, as for rate limits and server errors.
+	// A POST is only retryable after a rate limit or 503, since other server errors may follow a committed change.
+	Retryable bool
+
+	retryAfter time.Duration
+}
+
+// Error returns the HTTP status, the request, and Faire's message, falling back to the response body.
+func (e *APIError) Error() string {
+	detail := e.Message
+	if detail == "" {
+		detail = e.Body
+	}
+	if e.Code != "" {
+		detail = e.Code + ": " + detail
+	}
+	return fmt.Sprintf("faire API error (%s) on %s %s: %s", e.Status, e.Method, e.Path, detail)
+}
+
+// newAPIError describes resp, whose body has already been read, as the response to req.
+func newAPIError(req *http.Request, resp *http.Response, body []byte, now time.Time) *APIError {
+	code, message := parseFaireErrorBody(body)
+	return &APIError{
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Code:       code,
+		Message:    message,
+		Body:       strings.TrimSpace(string(body)),
+		Retryable:  isRetryableResponse(req.Method, resp.StatusCode),
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), now),
+	}
+}
+
+// parseFaireErrorBody returns the error type and message from a JSON error body, or empty strings when body is not one.
+// Faire reports errors as {"type": ..., "message": ...}; "code" and "error" are accepted as well.
+func parseFaireErrorBody(body []byte) (code, message string) {
+	var fields struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Error   any    `json:"error"`
+	}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return "", ""
+	}
+	code = fields.Type
+	if code == "" {
+		code = fields.Code
+	}
+	message = fields.Message
+	if text, ok := fields.Error.(string); ok && message == "" {
+		message = text
+	}
+	return strings.TrimSpace(code), strings.TrimSpace(message)
+}
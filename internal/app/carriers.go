@@ -0,0 +1,109 @@
+package app
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// FaireCarriers lists the carrier codes accepted by Faire's shipment endpoint.
+var FaireCarriers = []string{
+	"UPS",
+	"FEDEX",
+	"USPS",
+	"DHL",
+	"DHL_ECOMMERCE",
+	"CANADA_POST",
+	"PUROLATOR",
+	"CANPAR",
+	"ONTRAC",
+	"LASERSHIP",
+	"ROYAL_MAIL",
+	"AUSTRALIA_POST",
+}
+
+// builtinCarrierPrefixes maps normalized shipping-system carrier names to Faire carrier codes.
+// A name matches the longest prefix that ends at a word boundary, so "UPS Ground" and "FedEx Home Delivery" map
+// to UPS and FEDEX.
+var builtinCarrierPrefixes = map[string]string{
+	"UPS":                          "UPS",
+	"UNITED PARCEL SERVICE":        "UPS",
+	"FEDEX":                        "FEDEX",
+	"FED EX":                       "FEDEX",
+	"FEDERAL EXPRESS":              "FEDEX",
+	"USPS":                         "USPS",
+	"US POSTAL SERVICE":            "USPS",
+	"U S POSTAL SERVICE":           "USPS",
+	"UNITED STATES POSTAL SERVICE": "USPS",
+	"DHL":                          "DHL",
+	"DHL EXPRESS":                  "DHL",
+	"DHL ECOMMERCE":                "DHL_ECOMMERCE",
+	"DHL E COMMERCE":               "DHL_ECOMMERCE",
+	"CANADA POST":                  "CANADA_POST",
+	"POSTES CANADA":                "CANADA_POST",
+	"PUROLATOR":                    "PUROLATOR",
+	"CANPAR":                       "CANPAR",
+	"ONTRAC":                       "ONTRAC",
+	"LASERSHIP":                    "LASERSHIP",
+	"ROYAL MAIL":                   "ROYAL_MAIL",
+	"AUSTRALIA POST":               "AUSTRALIA_POST",
+}
+
+// CarrierMapper normalizes shipping-system carrier names to Faire carrier codes.
+type CarrierMapper struct {
+	overrides map[string]string
+}
+
+// NewCarrierMapper returns a mapper that consults overrides, keyed by shipping-system carrier name, before the
+// built-in table. Override keys are matched case-insensitively ignoring punctuation, and values are Faire codes.
+func NewCarrierMapper(overrides map[string]string) CarrierMapper {
+	mapper := CarrierMapper{overrides: make(map[string]string, len(overrides))}
+	for name, code := range overrides {
+		mapper.overrides[normalizeCarrierName(name)] = strings.ToUpper(strings.TrimSpace(code))
+	}
+	return mapper
+}
+
+// Normalize returns the Faire carrier code for name and whether the carrier is known.
+func (m CarrierMapper) Normalize(name string) (string, bool) {
+	normalized := normalizeCarrierName(name)
+	if normalized == "" {
+		return "", false
+	}
+	if code, ok := m.overrides[normalized]; ok && code != "" {
+		return code, true
+	}
+	if code := strings.ReplaceAll(normalized, " ", "_"); isFaireCarrier(code) {
+		return code, true
+	}
+
+	var prefixes []string
+	for prefix := range builtinCarrierPrefixes {
+		if normalized == prefix || strings.HasPrefix(normalized, prefix+" ") {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return "", false
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	return builtinCarrierPrefixes[prefixes[0]], true
+}
+
+// isFaireCarrier reports whether code is one of FaireCarriers.
+func isFaireCarrier(code string) bool {
+	for _, carrier := range FaireCarriers {
+		if carrier == code {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeCarrierName upper-cases name and collapses every run of punctuation and spaces to a single space.
+func normalizeCarrierName(name string) string {
+	fields := strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"testing"
+)
+
+func TestCarrierMapperNormalize(t *testing.T) {
+	mapper := NewCarrierMapper(map[string]string{"Speedy Freight": "ontrac", "UPS SurePost": "usps"})
+	tests := []struct {
+		name  string
+		want  string
+		known bool
+	}{
+		{name: "UPS", want: "UPS", known: true},
+		{name: "UPS Ground", want: "UPS", known: true},
+		{name: "ups-2nd day air", want: "UPS", known: true},
+		{name: "FedEx Home Delivery", want: "FEDEX", known: true},
+		{name: "Fed Ex Ground", want: "FEDEX", known: true},
+		{name: "U.S. Postal Service", want: "USPS", known: true},
+		{name: "DHL eCommerce", want: "DHL_ECOMMERCE", known: true},
+		{name: "DHL Express Worldwide", want: "DHL", known: true},
+		{name: "canada_post", want: "CANADA_POST", known: true},
+		{name: "speedy freight", want: "ONTRAC", known: true},
+		{name: "UPS SurePost", want: "USPS", known: true},
+		{name: "UPSX Logistics", known: false},
+		{name: "Pigeon", known: false},
+		{name: "  ", known: false},
+	}
+	for _, tt := range tests {
+		got, known := mapper.Normalize(tt.name)
+		if got != tt.want || known != tt.known {
+			t.Errorf("Normalize(%q) = %q, %v, want %q, %v", tt.name, got, known, tt.want, tt.known)
+		}
+	}
+}
+
+func TestReadShipmentsFlagsUnknownCarriers(t *testing.T) {
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,ORDER1,TRACK1,1.00,FedEx Home Delivery,Prepaid,0090671,BSC
+DOC2,ORDER2,TRACK2,1.00,Pigeon Post,Prepaid,0090671,BSC
+DOC3,ORDER3,TRACK3,1.00,Local Courier,Prepaid,0090671,BSC
+`
+	path := writeShipmentsCSV(t, csvContent)
+	profile := DefaultShipmentCSVProfile.clone()
+	profile.Carriers = map[string]string{"Local Courier": "ONTRAC"}
+
+	parsed, err := ReadShipmentsCSVWithProfile(path, profile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parsed.Shipments) != 2 || parsed.Shipments[0].Carrier != "FEDEX" || parsed.Shipments[1].Carrier != "ONTRAC" {
+		t.Errorf("shipments = %+v, want FEDEX and ONTRAC", parsed.Shipments)
+	}
+	if len(parsed.Skipped) != 1 || parsed.Skipped[0].Kind != SkipUnknownCarrier || parsed.Skipped[0].Line != 3 {
+		t.Errorf("skipped = %+v, want line 3 with an unknown carrier", parsed.Skipped)
+	}
+}
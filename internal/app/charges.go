@@ -0,0 +1,110 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChargeAllocation selects how a shipping charge is divided among the shipments it covers.
+type ChargeAllocation string
+
+const (
+	// ChargesAsListed keeps each row's charge unchanged.
+	ChargesAsListed ChargeAllocation = "as_listed"
+	// ChargesOnFirstPackage puts the whole charge on the first shipment and zero on the rest.
+	ChargesOnFirstPackage ChargeAllocation = "first_package"
+	// ChargesSplitEvenly divides the charge evenly, giving leftover cents to the first shipments.
+	ChargesSplitEvenly ChargeAllocation = "split_evenly"
+)
+
+// validate rejects allocations other than the known values; empty means ChargesAsListed.
+func (a ChargeAllocation) validate() error {
+	switch a {
+	case "", ChargesAsListed, ChargesOnFirstPackage, ChargesSplitEvenly:
+		return nil
+	}
+	return fmt.Errorf("unknown charge allocation %q (use %s, %s, or %s)", a, ChargesAsListed, ChargesOnFirstPackage, ChargesSplitEvenly)
+}
+
+// allocate divides totalCents among n shipments according to a.
+// ChargesAsListed returns nil because each shipment keeps its own charge.
+func (a ChargeAllocation) allocate(totalCents, n int) []int {
+	if n < 1 {
+		return nil
+	}
+	switch a {
+	case ChargesOnFirstPackage:
+		cents := make([]int, n)
+		cents[0] = totalCents
+		return cents
+	case ChargesSplitEvenly:
+		cents := make([]int, n)
+		for i := range cents {
+			cents[i] = totalCents / n
+			if i < totalCents%n {
+				cents[i]++
+			}
+		}
+		return cents
+	}
+	return nil
+}
+
+// allocatePackageCharges applies allocation to every order that ships in more than one row. Orders are matched by PO
+// number and sale source, and the order's charge is found by packageChargeTotal. Only the first row with each tracking
+// code counts as a package: later rows repeating it are posted as duplicates, so they are left a zero charge and the
+// order's charge goes to the packages that are posted.
+func allocatePackageCharges(shipments []Shipment, allocation ChargeAllocation, repeated bool) {
+	if allocation == "" || allocation == ChargesAsListed {
+		return
+	}
+	type orderKey struct {
+		orderID    string
+		saleSource string
+	}
+	var keys []orderKey
+	rows := make(map[orderKey][]int)
+	for i, s := range shipments {
+		key := orderKey{orderID: DisplayIDToOrderID(strings.TrimSpace(s.PONumber)), saleSource: strings.ToUpper(s.SaleSource)}
+		if _, exists := rows[key]; !exists {
+			keys = append(keys, key)
+		}
+		rows[key] = append(rows[key], i)
+	}
+	for _, key := range keys {
+		indices := rows[key]
+		if len(indices) < 2 {
+			continue
+		}
+		charges := make([]int, len(indices))
+		var packages []int
+		seen := make(map[string]bool, len(indices))
+		for i, index := range indices {
+			charges[i] = shipments[index].MakerCostCents
+			trackingCode := normalizeTrackingCode(shipments[index].TrackingCode)
+			if trackingCode != "" && seen[trackingCode] {
+				shipments[index].MakerCostCents = 0
+				continue
+			}
+			seen[trackingCode] = true
+			packages = append(packages, index)
+		}
+		for i, cents := range allocation.allocate(packageChargeTotal(charges, repeated), len(packages)) {
+			shipments[packages[i]].MakerCostCents = cents
+		}
+	}
+}
+
+// packageChargeTotal returns the charge for an order whose rows list charges. When repeated is set the export repeats
+// the order's charge on each row, or lists it only on the first, so the first nonzero charge is the order's; otherwise
+// each row was charged separately and the charges are added up.
+func packageChargeTotal(charges []int, repeated bool) int {
+	total := 0
+	for _, cents := range charges {
+		if repeated && cents != 0 {
+			return cents
+		}
+		total += cents
+	}
+	return total
+}
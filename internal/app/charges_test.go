@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestChargeAllocationAllocate(t *testing.T) {
+	tests := []struct {
+		allocation ChargeAllocation
+		total      int
+		n          int
+		want       []int
+	}{
+		{allocation: ChargesAsListed, total: 1000, n: 3, want: nil},
+		{allocation: ChargesOnFirstPackage, total: 1000, n: 3, want: []int{1000, 0, 0}},
+		{allocation: ChargesSplitEvenly, total: 1000, n: 3, want: []int{334, 333, 333}},
+		{allocation: ChargesSplitEvenly, total: 1, n: 2, want: []int{1, 0}},
+		{allocation: ChargesSplitEvenly, total: 1000, n: 0, want: nil},
+	}
+	for _, tt := range tests {
+		if got := tt.allocation.allocate(tt.total, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s.allocate(%d, %d) = %v, want %v", tt.allocation, tt.total, tt.n, got, tt.want)
+		}
+	}
+	if err := ChargeAllocation("evenly").validate(); err == nil {
+		t.Errorf("validate() accepted an unknown allocation")
+	}
+}
+
+func TestPackageChargeTotal(t *testing.T) {
+	tests := []struct {
+		charges  []int
+		repeated bool
+		want     int
+	}{
+		{charges: []int{1000, 1000, 1000}, want: 3000},
+		{charges: []int{1000, 1000, 1000}, repeated: true, want: 1000},
+		{charges: []int{1000, 0, 0}, want: 1000},
+		{charges: []int{1000, 0, 0}, repeated: true, want: 1000},
+		{charges: []int{0, 0}, repeated: true, want: 0},
+		{charges: []int{1000, 250, 0}, want: 1250},
+		{charges: []int{0, 300, 300}, repeated: true, want: 300},
+	}
+	for _, tt := range tests {
+		if got := packageChargeTotal(tt.charges, tt.repeated); got != tt.want {
+			t.Errorf("packageChargeTotal(%v, %t) = %d, want %d", tt.charges, tt.repeated, got, tt.want)
+		}
+	}
+}
+
+// readPackageCharges returns the charge of each shipment parsed from path with the given package charge settings.
+func readPackageCharges(t *testing.T, path string, allocation ChargeAllocation, repeated bool) []int {
+	t.Helper()
+	profile := DefaultShipmentCSVProfile.clone()
+	profile.PackageCharges = allocation
+	profile.PackageChargesRepeated = repeated
+	parsed, err := ReadShipmentsCSVWithProfile(path, profile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var charges []int
+	for _, shipment := range parsed.Shipments {
+		charges = append(charges, shipment.MakerCostCents)
+	}
+	return charges
+}
+
+func TestReadShipmentsSumsPackageCharges(t *testing.T) {
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,ORDER1,1Z0000000000000001,10.00,UPS,Prepaid,0090671,BSC
+DOC2,ORDER1,1Z0000000000000002,6.00,UPS,Prepaid,0090671,BSC
+DOC3,ORDER1,1Z0000000000000003,5.00,UPS,Prepaid,0090671,BSC
+DOC4,ORDER2,1Z0000000000000004,10.00,UPS,Prepaid,0090671,BSC
+DOC5,ORDER2,1Z0000000000000005,10.00,UPS,Prepaid,0090671,BSC
+`
+	path := writeShipmentsCSV(t, csvContent)
+
+	// Two boxes charged $10 each are reimbursed $20 unless the profile says the charge is repeated.
+	tests := map[ChargeAllocation][]int{
+		ChargesAsListed:       {1000, 600, 500, 1000, 1000},
+		ChargesOnFirstPackage: {2100, 0, 0, 2000, 0},
+		ChargesSplitEvenly:    {700, 700, 700, 1000, 1000},
+	}
+	for allocation, want := range tests {
+		if got := readPackageCharges(t, path, allocation, false); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s charges = %v, want %v", allocation, got, want)
+		}
+	}
+}
+
+func TestReadShipmentsAllocatesRepeatedPackageCharges(t *testing.T) {
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,ORDER1,1Z0000000000000001,10.00,UPS,Prepaid,0090671,BSC
+DOC2,ORDER2,1Z0000000000000002,4.00,UPS,Prepaid,0090671,BSC
+DOC3,order1,1Z0000000000000003,10.00,UPS,Prepaid,0090671,BSC
+DOC4,ORDER1,1Z0000000000000004,10.00,UPS,Prepaid,0090671,BSC
+DOC5,ORDER1,1Z0000000000000005,10.00,UPS,Prepaid,0090671,SM
+`
+	path := writeShipmentsCSV(t, csvContent)
+
+	tests := map[ChargeAllocation][]int{
+		ChargesAsListed:       {1000, 400, 1000, 1000, 1000},
+		ChargesOnFirstPackage: {1000, 400, 0, 0, 1000},
+		ChargesSplitEvenly:    {334, 400, 333, 333, 1000},
+	}
+	for allocation, want := range tests {
+		if got := readPackageCharges(t, path, allocation, true); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s charges = %v, want %v", allocation, got, want)
+		}
+	}
+}
+
+func TestProcessShipmentsPostsWholeChargeForSharedTrackingCode(t *testing.T) {
+	t.Setenv("BSC_API_TOKEN", "dummy-token")
+	// Three boxes under one master tracking code are posted once, so that shipment carries all three charges.
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,ORDER1,1Z0000000000000001,10.00,UPS,Prepaid,0090671,BSC
+DOC2,ORDER1,1Z0000000000000001,10.00,UPS,Prepaid,0090671,BSC
+DOC3,ORDER1,1Z0000000000000001,10.00,UPS,Prepaid,0090671,BSC
+DOC4,ORDER1,1Z0000000000000002,5.00,UPS,Prepaid,0090671,BSC
+`
+	path := writeShipmentsCSV(t, csvContent)
+
+	tests := map[ChargeAllocation][]int{
+		ChargesOnFirstPackage: {3500, 0},
+		ChargesSplitEvenly:    {1750, 1750},
+	}
+	for allocation, want := range tests {
+		profile := DefaultShipmentCSVProfile.clone()
+		profile.PackageCharges = allocation
+		options := ShipmentProcessOptions{SkipExistingShipments: true, Profile: &profile}
+		results, err := ProcessShipmentsWithOptions(context.Background(), path, &MockFaireClient{AutoCreateOrders: true}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var got []int
+		for _, payload := range results.Processed {
+			got = append(got, payload.MakerCostCents)
+		}
+		if !reflect.DeepEqual(got, want) || len(results.Duplicates) != 2 {
+			t.Errorf("%s posted charges = %v with %d duplicates, want %v with 2", allocation, got, len(results.Duplicates), want)
+		}
+	}
+}
@@ -29,17 +29,8 @@ type Order struct {
 		IncludesTester bool      `json:"includes_tester"`
 		Discounts      []any     `json:"discounts"`
 	} `json:"items"`
-	Shipments []struct {
-		ID             string    `json:"id"`
-		OrderID        string    `json:"order_id"`
-		MakerCostCents int       `json:"maker_cost_cents"`
-		Carrier        string    `json:"carrier"`
-		TrackingCode   string    `json:"tracking_code"`
-		CreatedAt      time.Time `json:"created_at"`
-		UpdatedAt      time.Time `json:"updated_at"`
-		ShippingType   string    `json:"shipping_type"`
-	} `json:"shipments"`
-	Address struct {
+	Shipments []OrderShipment `json:"shipments"`
+	Address   struct {
 		Name        string `json:"name"`
 		Address1    string `json:"address1"`
 		Address2    string `json:"address2"`
@@ -85,6 +76,18 @@ type Order struct {
 	SalesRepName                          string    `json:"sales_rep_name"`
 }
 
+// OrderShipment is a shipment that Faire has recorded on an order.
+type OrderShipment struct {
+	ID             string    `json:"id"`
+	OrderID        string    `json:"order_id"`
+	MakerCostCents int       `json:"maker_cost_cents"`
+	Carrier        string    `json:"carrier"`
+	TrackingCode   string    `json:"tracking_code"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	ShippingType   string    `json:"shipping_type"`
+}
+
 type Orders struct {
 	Page   int     `json:"page"`
 	Limit  int     `json:"limit"`
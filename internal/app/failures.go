@@ -0,0 +1,165 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// FailureKind names the cause of a failed shipment, so failures can be grouped and each group given advice.
+type FailureKind string
+
+const (
+	// FailureOrderNotFound marks a shipment whose order Faire could not find for the sale source's token.
+	FailureOrderNotFound FailureKind = "order_not_found"
+	// FailureInvalidCarrier marks a shipment Faire rejected because of its carrier.
+	FailureInvalidCarrier FailureKind = "invalid_carrier"
+	// FailureInvalidTrackingCode marks a shipment Faire rejected because of its tracking code.
+	FailureInvalidTrackingCode FailureKind = "invalid_tracking_code"
+	// FailureUnauthorized marks a shipment whose sale source has no API token or one Faire rejected.
+	FailureUnauthorized FailureKind = "unauthorized"
+	// FailureOrderState marks a shipment not posted because its order should not be shipped.
+	FailureOrderState FailureKind = "order_state"
+	// FailureRateLimited marks a shipment that was still rate limited after every retry.
+	FailureRateLimited FailureKind = "rate_limited"
+	// FailureFaireUnavailable marks a shipment that still met a Faire server error after every retry.
+	FailureFaireUnavailable FailureKind = "faire_unavailable"
+	// FailureRejected marks a shipment Faire rejected for another reason.
+	FailureRejected FailureKind = "rejected"
+	// FailureNetwork marks a shipment whose request could not reach Faire or timed out.
+	FailureNetwork FailureKind = "network"
+	// FailureNotSent marks a shipment not sent because the run was canceled.
+	FailureNotSent FailureKind = "not_sent"
+	// FailureOutcomeUnknown marks a shipment whose request was in flight when the run was canceled, so Faire may
+	// have created it.
+	FailureOutcomeUnknown FailureKind = "outcome_unknown"
+	// FailureOther marks a shipment that failed for any other reason.
+	FailureOther FailureKind = "other"
+)
+
+// failureKindDescriptions holds the heading and advice shown for each FailureKind.
+var failureKindDescriptions = map[FailureKind]struct{ title, advice string }{
+	FailureOrderNotFound: {"Order not found",
+		"Check that the PO number matches the order's display ID in Faire and that the sale source is the brand that received the order."},
+	FailureInvalidCarrier: {"Invalid carrier",
+		"Faire did not accept the carrier. Map the shipping system's carrier name to a Faire carrier code in the shipment profile's carriers setting, then retry."},
+	FailureInvalidTrackingCode: {"Invalid tracking code",
+		"Check the tracking code in the shipping system. Codes changed by a spreadsheet must be exported again as text."},
+	FailureUnauthorized: {"Unauthorized token",
+		"The sale source's API token is missing or was rejected. Check its token in the .env file, then retry."},
+	FailureOrderState: {"Order cannot be shipped",
+		"The order is canceled, delivered, or has a cancellation request. Confirm with the retailer in Faire before adding the shipment."},
+	FailureRateLimited: {"Rate limited",
+		"Faire is limiting requests. Wait a few minutes, then retry."},
+	FailureFaireUnavailable: {"Faire unavailable",
+		"Faire reported a server error. Retry later."},
+	FailureRejected: {"Rejected by Faire",
+		"Faire rejected the shipment. Read the error for the field to correct, then retry."},
+	FailureNetwork: {"Network error",
+		"Faire could not be reached. Check the internet connection, then retry."},
+	FailureNotSent: {"Not sent",
+		"The run was canceled before these shipments were sent. Retry them."},
+	FailureOutcomeUnknown: {"Outcome unknown",
+		"The run was canceled while these shipments were being sent, so Faire may have created them. Check the order before retrying, or retry with existing shipments skipped."},
+	FailureOther: {"Other errors",
+		"Read each error for details, then retry."},
+}
+
+// Title returns a short heading for failures of kind k.
+func (k FailureKind) Title() string {
+	if description, ok := failureKindDescriptions[k]; ok {
+		return description.title
+	}
+	return failureKindDescriptions[FailureOther].title
+}
+
+// Advice returns what the user can do about failures of kind k.
+func (k FailureKind) Advice() string {
+	if description, ok := failureKindDescriptions[k]; ok {
+		return description.advice
+	}
+	return failureKindDescriptions[FailureOther].advice
+}
+
+// errOrderNotShippable marks shipments that were not posted because of their order's state.
+var errOrderNotShippable = errors.New("shipment not posted")
+
+// errNotSent marks shipments that were never sent because the run was canceled first.
+var errNotSent = errors.New("not sent")
+
+// errMissingToken marks shipments that were not sent because their sale source has no API token.
+var errMissingToken = errors.New("shipment not sent")
+
+// classifyFailure returns the FailureKind for err, the error that stopped a shipment.
+func classifyFailure(err error) FailureKind {
+	if errors.Is(err, errNotSent) {
+		return FailureNotSent
+	}
+	if errors.Is(err, context.Canceled) {
+		// Only requests that were already in flight fail with a bare cancellation.
+		return FailureOutcomeUnknown
+	}
+	if errors.Is(err, errOrderNotShippable) {
+		return FailureOrderState
+	}
+	if errors.Is(err, errMissingToken) {
+		return FailureUnauthorized
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+			return FailureUnauthorized
+		case apiErr.StatusCode == http.StatusNotFound:
+			return FailureOrderNotFound
+		case apiErr.StatusCode == http.StatusTooManyRequests:
+			return FailureRateLimited
+		case apiErr.StatusCode >= http.StatusInternalServerError:
+			return FailureFaireUnavailable
+		}
+		detail := strings.ToLower(apiErr.Code + " " + apiErr.Message + " " + apiErr.Body)
+		switch {
+		case strings.Contains(detail, "carrier"):
+			return FailureInvalidCarrier
+		case strings.Contains(detail, "tracking"):
+			return FailureInvalidTrackingCode
+		}
+		return FailureRejected
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return FailureNetwork
+	}
+	return FailureOther
+}
+
+// FailureGroup lists failed shipments that share a cause.
+type FailureGroup struct {
+	Kind     FailureKind
+	Payloads []ShipmentPayload
+}
+
+// GroupFailures groups failed by FailureKind, ordering groups by their first shipment.
+// Payloads without a kind, such as those saved by an older version, are grouped under FailureOther.
+func GroupFailures(failed []ShipmentPayload) []FailureGroup {
+	var groups []FailureGroup
+	indexByKind := make(map[FailureKind]int)
+	for _, payload := range failed {
+		kind := payload.FailureKind
+		if _, known := failureKindDescriptions[kind]; !known {
+			kind = FailureOther
+		}
+		index, exists := indexByKind[kind]
+		if !exists {
+			index = len(groups)
+			indexByKind[kind] = index
+			groups = append(groups, FailureGroup{Kind: kind})
+		}
+		groups[index].Payloads = append(groups[index].Payloads, payload)
+	}
+	return groups
+}
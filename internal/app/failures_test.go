@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want FailureKind
+	}{
+		{"canceled before sending", fmt.Errorf("%w: %w", errNotSent, context.Canceled), FailureNotSent},
+		{"canceled in flight", fmt.Errorf("post shipments: %w", context.Canceled), FailureOutcomeUnknown},
+		{"blocked order", fmt.Errorf("order is CANCELED; %w", errOrderNotShippable), FailureOrderState},
+		{"unauthorized", &APIError{StatusCode: http.StatusUnauthorized}, FailureUnauthorized},
+		{"order lookup not found", fmt.Errorf("check order before posting: %w", &APIError{StatusCode: http.StatusNotFound}), FailureOrderNotFound},
+		{"rate limited", fmt.Errorf("%w (after 4 attempts)", &APIError{StatusCode: http.StatusTooManyRequests}), FailureRateLimited},
+		{"server error", &APIError{StatusCode: http.StatusBadGateway}, FailureFaireUnavailable},
+		{"invalid carrier", &APIError{StatusCode: http.StatusBadRequest, Code: "INVALID_CARRIER"}, FailureInvalidCarrier},
+		{"invalid tracking code", &APIError{StatusCode: http.StatusUnprocessableEntity, Message: "Tracking code is invalid"}, FailureInvalidTrackingCode},
+		{"other rejection", &APIError{StatusCode: http.StatusBadRequest, Body: "bad request"}, FailureRejected},
+		{"network", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, FailureNetwork},
+		{"other", errors.New("simulated failure"), FailureOther},
+	}
+	for _, tt := range tests {
+		if got := classifyFailure(tt.err); got != tt.want {
+			t.Errorf("classifyFailure(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGroupFailures(t *testing.T) {
+	failed := []ShipmentPayload{
+		{TrackingCode: "T1", FailureKind: FailureOrderNotFound},
+		{TrackingCode: "T2", FailureKind: FailureInvalidCarrier},
+		{TrackingCode: "T3"},
+		{TrackingCode: "T4", FailureKind: FailureOrderNotFound},
+	}
+	var got []string
+	for _, group := range GroupFailures(failed) {
+		for _, payload := range group.Payloads {
+			got = append(got, string(group.Kind)+":"+payload.TrackingCode)
+		}
+	}
+	want := []string{"order_not_found:T1", "order_not_found:T4", "invalid_carrier:T2", "other:T3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupFailures() = %v, want %v", got, want)
+	}
+	if FailureKind("unknown").Advice() != FailureOther.Advice() {
+		t.Error("an unknown FailureKind should share FailureOther's advice")
+	}
+}
@@ -2,28 +2,48 @@ package app
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/Fepozopo/bsc-faire/internal/version"
 	"github.com/joho/godotenv"
 )
 
 // FaireClient sends authenticated requests to the Faire Orders API.
 type FaireClient struct {
 	BaseURL string
+	// Retry controls how transient failures are retried; the zero value sends each request once.
+	Retry RetryPolicy
+	// Timeout bounds each attempt; zero leaves attempts bounded only by the request context.
+	Timeout time.Duration
+	// HTTPClient sends requests; nil uses http.DefaultClient. Its Transport can be replaced to inject a RoundTripper.
+	HTTPClient *http.Client
+	// UserAgent is sent with every request; empty sends Go's default.
+	UserAgent string
+	// Logger records each request attempt, with access tokens redacted; nil disables logging.
+	Logger *slog.Logger
+
+	// sleep waits between retries; tests replace it to avoid real delays.
+	sleep func(ctx context.Context, delay time.Duration) error
 }
 
 // FaireClientInterface defines the Faire operations used by the application.
 type FaireClientInterface interface {
-	AddShipment(payload ShipmentPayload, apiToken string) error
-	GetAllOrders(apiToken string, limit int, page int, excludedStates string) ([]byte, error)
-	GetOrderByID(orderIdentifier string, apiToken string) ([]byte, error)
+	AddShipments(ctx context.Context, payloads []ShipmentPayload, apiToken string) ([]OrderShipment, error)
+	GetAllOrders(ctx context.Context, apiToken string, limit int, page int, excludedStates string) ([]byte, error)
+	GetOrderByID(ctx context.Context, orderIdentifier string, apiToken string) ([]byte, error)
 }
 
 // ShipmentRequest is the request body accepted by Faire's shipment endpoint.
@@ -40,36 +60,145 @@ type ShipmentPayload struct {
 	ShippingType   string `json:"shipping_type"`
 	SaleSource     string `json:"sale_source"`
 	ErrorMsg       string `json:"error_msg"`
+	// FailureKind groups a failed shipment by cause; it is empty unless the shipment failed.
+	FailureKind FailureKind `json:"failure_kind,omitempty"`
+	// Created is the shipment Faire recorded for this payload, when the API response identified it.
+	Created *OrderShipment `json:"created,omitempty"`
 }
 
-// NewFaireClient loads the Faire base URL from the environment and returns a client.
-func NewFaireClient() *FaireClient {
+// NewFaireClient loads the Faire base URL and the HTTPConfigFromEnv settings from the environment and returns a client.
+func NewFaireClient() (*FaireClient, error) {
 	_ = godotenv.Load()
+	config, err := HTTPConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := NewHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+	logger, err := DefaultLogger()
+	if err != nil {
+		return nil, fmt.Errorf("%w; set FAIRE_LOG_LEVEL=off to turn logging off", err)
+	}
 	return &FaireClient{
-		BaseURL: os.Getenv("FAIRE_BASE_URL"),
+		BaseURL:    os.Getenv("FAIRE_BASE_URL"),
+		Retry:      DefaultRetryPolicy,
+		Timeout:    config.Timeout,
+		HTTPClient: httpClient,
+		UserAgent:  "bsc-faire/" + version.Version,
+		Logger:     logger,
+	}, nil
+}
+
+// AddShipment adds payload to its order using apiToken and returns the shipments Faire created or an API or transport error.
+func (c *FaireClient) AddShipment(ctx context.Context, payload ShipmentPayload, apiToken string) ([]OrderShipment, error) {
+	return c.AddShipments(ctx, []ShipmentPayload{payload}, apiToken)
+}
+
+// AddShipments adds every payload to their shared order in one request using apiToken and returns the shipments Faire created.
+// All payloads must belong to the same order because Faire's shipment endpoint is scoped to one order.
+// When a request fails in a way that leaves open whether Faire created the shipments, the order is fetched again and
+// only the shipments it does not already have are resent, so a retry cannot add a duplicate.
+// Canceling ctx abandons the request and any remaining retries.
+func (c *FaireClient) AddShipments(ctx context.Context, payloads []ShipmentPayload, apiToken string) ([]OrderShipment, error) {
+	orderID, err := shipmentsOrderID(payloads)
+	if err != nil {
+		return nil, err
+	}
+
+	var confirmed []OrderShipment
+	attempts := c.Retry.attempts()
+	for attempt := 1; ; attempt++ {
+		created, err := c.postShipments(ctx, orderID, payloads, apiToken)
+		if err == nil {
+			return append(confirmed, created...), nil
+		}
+		if attempt >= attempts || ctx.Err() != nil || !mayHaveBeenApplied(err) {
+			return nil, err
+		}
+		if sleepErr := c.wait(ctx, c.Retry.backoff(attempt, 0)); sleepErr != nil {
+			return nil, fmt.Errorf("%w (retry interrupted: %v)", err, sleepErr)
+		}
+
+		order, fetchErr := fetchOrder(ctx, c, orderID, apiToken)
+		if fetchErr != nil {
+			return nil, fmt.Errorf("%w (could not check the order for shipments before resending: %v)", err, fetchErr)
+		}
+		existing := orderTrackingCodes(order)
+		sent := make(map[string]struct{}, len(payloads))
+		var remaining []ShipmentPayload
+		for _, payload := range payloads {
+			trackingCode := normalizeTrackingCode(payload.TrackingCode)
+			if trackingCode != "" {
+				sent[trackingCode] = struct{}{}
+				if _, ok := existing[trackingCode]; ok {
+					continue
+				}
+			}
+			remaining = append(remaining, payload)
+		}
+		for _, shipment := range order.Shipments {
+			if _, ok := sent[normalizeTrackingCode(shipment.TrackingCode)]; ok {
+				confirmed = append(confirmed, shipment)
+			}
+		}
+		if len(remaining) == 0 {
+			return confirmed, nil
+		}
+		payloads = remaining
 	}
 }
 
-// AddShipment adds payload to its order using apiToken and returns an API or transport error.
-func (c *FaireClient) AddShipment(payload ShipmentPayload, apiToken string) error {
-	endpoint := fmt.Sprintf("%s/orders/%s/shipments", strings.TrimRight(c.BaseURL, "/"), url.PathEscape(payload.OrderID))
-	body, err := json.Marshal(ShipmentRequest{Shipments: []ShipmentPayload{payload}})
+// postShipments sends payloads to orderID's shipment endpoint once, subject to readResponse's retries.
+func (c *FaireClient) postShipments(ctx context.Context, orderID string, payloads []ShipmentPayload, apiToken string) ([]OrderShipment, error) {
+	endpoint := fmt.Sprintf("%s/orders/%s/shipments", strings.TrimRight(c.BaseURL, "/"), url.PathEscape(orderID))
+	request := ShipmentRequest{Shipments: make([]ShipmentPayload, len(payloads))}
+	for i, payload := range payloads {
+		// Results from an earlier attempt are local bookkeeping and are not sent to Faire.
+		payload.ErrorMsg = ""
+		payload.FailureKind = ""
+		payload.Created = nil
+		request.Shipments[i] = payload
+	}
+	body, err := json.Marshal(request)
 	if err != nil {
-		return fmt.Errorf("marshal shipment request: %w", err)
+		return nil, fmt.Errorf("marshal shipment request: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBuffer(body))
 	if err != nil {
-		return fmt.Errorf("create shipment request: %w", err)
+		return nil, fmt.Errorf("create shipment request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-FAIRE-ACCESS-TOKEN", apiToken)
 
-	return c.doRequest(req)
+	response, err := c.readResponse(req)
+	if err != nil {
+		return nil, err
+	}
+	return parseCreatedShipments(response), nil
+}
+
+// parseCreatedShipments decodes the shipments in a successful shipment response, which Faire returns either as a
+// list or wrapped in a "shipments" field. The shipments were already created, so an unrecognized body yields none
+// rather than an error that would invite a duplicate retry.
+func parseCreatedShipments(body []byte) []OrderShipment {
+	var shipments []OrderShipment
+	if err := json.Unmarshal(body, &shipments); err == nil {
+		return shipments
+	}
+	var wrapped struct {
+		Shipments []OrderShipment `json:"shipments"`
+	}
+	if err := json.Unmarshal(body, &wrapped); err == nil {
+		return wrapped.Shipments
+	}
+	return nil
 }
 
 // GetAllOrders returns one page of orders while excluding the supplied Faire order states.
-func (c *FaireClient) GetAllOrders(apiToken string, limit int, page int, excludedStates string) ([]byte, error) {
+func (c *FaireClient) GetAllOrders(ctx context.Context, apiToken string, limit int, page int, excludedStates string) ([]byte, error) {
 	endpoint, err := url.Parse(strings.TrimRight(c.BaseURL, "/") + "/orders")
 	if err != nil {
 		return nil, fmt.Errorf("parse orders endpoint: %w", err)
@@ -81,7 +210,7 @@ func (c *FaireClient) GetAllOrders(apiToken string, limit int, page int, exclude
 	query.Set("excluded_states", excludedStates)
 	endpoint.RawQuery = query.Encode()
 
-	req, err := http.NewRequest(http.MethodGet, endpoint.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("create orders request: %w", err)
 	}
@@ -91,14 +220,14 @@ func (c *FaireClient) GetAllOrders(apiToken string, limit int, page int, exclude
 }
 
 // GetOrderByID returns the order identified by a display ID or a Faire bo_ order ID.
-func (c *FaireClient) GetOrderByID(orderIdentifier string, apiToken string) ([]byte, error) {
+func (c *FaireClient) GetOrderByID(ctx context.Context, orderIdentifier string, apiToken string) ([]byte, error) {
 	orderID := OrderIdentifierToOrderID(orderIdentifier)
 	if orderID == "" {
 		return nil, fmt.Errorf("order identifier cannot be empty")
 	}
 
 	endpoint := fmt.Sprintf("%s/orders/%s", strings.TrimRight(c.BaseURL, "/"), url.PathEscape(orderID))
-	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return nil, fmt.Errorf("create order request: %w", err)
 	}
@@ -107,36 +236,146 @@ func (c *FaireClient) GetOrderByID(orderIdentifier string, apiToken string) ([]b
 	return c.readResponse(req)
 }
 
-// doRequest sends req and returns a descriptive error unless Faire returns a successful status.
-func (c *FaireClient) doRequest(req *http.Request) error {
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
+// shipmentsOrderID returns the order shared by payloads and rejects empty or mixed-order batches.
+func shipmentsOrderID(payloads []ShipmentPayload) (string, error) {
+	if len(payloads) == 0 {
+		return "", fmt.Errorf("shipment request must contain at least one shipment")
 	}
-	defer func() { _ = resp.Body.Close() }()
+	orderID := payloads[0].OrderID
+	for _, payload := range payloads[1:] {
+		if payload.OrderID != orderID {
+			return "", fmt.Errorf("shipment request mixes orders %q and %q", orderID, payload.OrderID)
+		}
+	}
+	return orderID, nil
+}
+
+// readResponse sends req, retrying transient failures according to c.Retry, and returns the body of a successful response.
+func (c *FaireClient) readResponse(req *http.Request) ([]byte, error) {
+	attempts := c.Retry.attempts()
+	for attempt := 1; ; attempt++ {
+		body, err := c.sendOnce(req)
+		if err == nil {
+			return body, nil
+		}
+		if attempt >= attempts || !isRetryable(req, err) {
+			if attempt > 1 {
+				return nil, fmt.Errorf("%w (after %d attempts)", err, attempt)
+			}
+			return nil, err
+		}
 
-	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
-		return nil
+		var retryAfter time.Duration
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			retryAfter = apiErr.retryAfter
+		}
+		if sleepErr := c.wait(req.Context(), c.Retry.backoff(attempt, retryAfter)); sleepErr != nil {
+			return nil, fmt.Errorf("%w (retry interrupted: %v)", err, sleepErr)
+		}
 	}
+}
 
-	body, _ := io.ReadAll(resp.Body)
-	return fmt.Errorf("faire API error (%s): %s", resp.Status, strings.TrimSpace(string(body)))
+// wait pauses for delay between attempts or until ctx is done.
+func (c *FaireClient) wait(ctx context.Context, delay time.Duration) error {
+	if c.sleep != nil {
+		return c.sleep(ctx, delay)
+	}
+	return sleepContext(ctx, delay)
 }
 
-// readResponse sends req and returns its body when Faire returns a successful status.
-func (c *FaireClient) readResponse(req *http.Request) ([]byte, error) {
-	resp, err := http.DefaultClient.Do(req)
+// sendOnce makes a single attempt at req, bounded by c.Timeout, and returns the body of a successful response.
+func (c *FaireClient) sendOnce(req *http.Request) ([]byte, error) {
+	ctx := req.Context()
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	// A failure before any connection was made means Faire never saw the attempt, which makes it safe to repeat.
+	var connected atomic.Bool
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) { connected.Store(true) },
+	})
+	attemptReq := req.Clone(ctx)
+	if req.GetBody != nil {
+		// Each attempt needs a fresh copy of the request body.
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("copy request body: %w", err)
+		}
+		attemptReq.Body = body
+	}
+	if c.UserAgent != "" {
+		attemptReq.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	start := time.Now()
+	resp, err := httpClient.Do(attemptReq)
 	if err != nil {
+		c.logExchange(attemptReq, nil, nil, time.Since(start), err)
+		if !connected.Load() {
+			return nil, &unsentError{err: err}
+		}
 		return nil, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	body, err := io.ReadAll(resp.Body)
+	c.logExchange(attemptReq, resp, body, time.Since(start), err)
 	if err != nil {
 		return nil, fmt.Errorf("read Faire API response: %w", err)
 	}
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
-		return nil, fmt.Errorf("faire API error (%s): %s", resp.Status, strings.TrimSpace(string(body)))
+		return nil, newAPIError(req, resp, body, time.Now())
 	}
 	return body, nil
 }
+
+// logExchange records one attempt at req. Every attempt is logged with its method, URL, status, and latency; failed
+// attempts are logged as warnings with the raw response body, and the debug level adds the redacted headers and
+// both bodies.
+func (c *FaireClient) logExchange(req *http.Request, resp *http.Response, body []byte, latency time.Duration, err error) {
+	if c.Logger == nil {
+		return
+	}
+	ctx := req.Context()
+	level := slog.LevelInfo
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.Redacted()),
+		slog.Duration("latency", latency),
+	}
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+		if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+			level = slog.LevelWarn
+		}
+	}
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	debug := c.Logger.Enabled(ctx, slog.LevelDebug)
+	if debug {
+		attrs = append(attrs, slog.Any("request_headers", redactHeaders(req.Header)))
+		if req.GetBody != nil {
+			if requestBody, err := req.GetBody(); err == nil {
+				data, _ := io.ReadAll(requestBody)
+				attrs = append(attrs, slog.String("request_body", truncateLogBody(data)))
+			}
+		}
+		if resp != nil {
+			attrs = append(attrs, slog.Any("response_headers", redactHeaders(resp.Header)))
+		}
+	}
+	if resp != nil && (debug || level > slog.LevelInfo) {
+		attrs = append(attrs, slog.String("response_body", truncateLogBody(body)))
+	}
+	c.Logger.LogAttrs(ctx, level, "faire request", attrs...)
+}
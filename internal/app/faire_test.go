@@ -1,16 +1,20 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
 func TestNewFaireClientPanic(t *testing.T) {
 	os.Unsetenv("BSC_API_TOKEN")
+	t.Setenv("FAIRE_LOG_LEVEL", "off")
 	defer func() {
 		recover()
 	}()
@@ -23,18 +27,10 @@ func TestAddShipment(t *testing.T) {
 0083419,88NJSQS3DD,1Z972Y3Y0301141377,7/16/2025,0,UPS,Consignee,0090671,SM
 0083510,ATG32GC3XX,1Z972Y3Y0312933410,7/17/2025,17.79,UPS,Prepaid,0090671,BSC`
 
-	tmpFile, err := os.CreateTemp("", "test_shipments_*.csv")
-	if err != nil {
-		t.Fatalf("failed to create temp file: %v", err)
-	}
-	defer os.Remove(tmpFile.Name())
-	if _, err := tmpFile.Write([]byte(csvContent)); err != nil {
-		t.Fatalf("failed to write to temp file: %v", err)
-	}
-	tmpFile.Close()
+	path := writeShipmentsCSV(t, csvContent)
 
 	// Step 2: Parse CSV
-	shipments, err := ParseShipmentsCSV(tmpFile.Name())
+	shipments, err := ParseShipmentsCSV(path)
 	if err != nil {
 		t.Fatalf("failed to parse shipments: %v", err)
 	}
@@ -84,7 +80,7 @@ func TestAddShipment(t *testing.T) {
 		BaseURL: server.URL,
 	}
 	for i, payload := range payloads {
-		err = client.AddShipment(payload, "dummy-token")
+		_, err = client.AddShipment(context.Background(), payload, "dummy-token")
 		if err != nil {
 			t.Fatalf("AddShipment failed for shipment %d: %v", i+1, err)
 		}
@@ -109,3 +105,126 @@ func TestAddShipment(t *testing.T) {
 		}
 	}
 }
+
+func TestAddShipmentsSendsOneRequestPerOrder(t *testing.T) {
+	var requests []ShipmentRequest
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ShipmentRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode body: %v", err)
+		}
+		requests = append(requests, request)
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &FaireClient{BaseURL: server.URL}
+	payloads := []ShipmentPayload{
+		{OrderID: "bo_abc", TrackingCode: "TRACK1", Carrier: "UPS"},
+		{OrderID: "bo_abc", TrackingCode: "TRACK2", Carrier: "UPS"},
+	}
+	if _, err := client.AddShipments(context.Background(), payloads, "dummy-token"); err != nil {
+		t.Fatalf("AddShipments failed: %v", err)
+	}
+	if len(requests) != 1 || len(requests[0].Shipments) != 2 {
+		t.Fatalf("expected one request with 2 shipments, got %+v", requests)
+	}
+	if paths[0] != "/orders/bo_abc/shipments" {
+		t.Errorf("request path = %q, want /orders/bo_abc/shipments", paths[0])
+	}
+
+	mixed := append(payloads, ShipmentPayload{OrderID: "bo_other"})
+	if _, err := client.AddShipments(context.Background(), mixed, "dummy-token"); err == nil {
+		t.Error("expected an error for shipments from different orders")
+	}
+	if len(requests) != 1 {
+		t.Errorf("mixed-order batch was sent to Faire")
+	}
+}
+
+func TestAddShipmentsReturnsCreatedShipments(t *testing.T) {
+	responses := []string{
+		`[{"id": "shp_1", "order_id": "bo_abc", "carrier": "UPS", "tracking_code": "TRACK1", "created_at": "2026-01-02T03:04:05Z"}]`,
+		`{"shipments": [{"id": "shp_2", "order_id": "bo_abc", "carrier": "FEDEX", "tracking_code": "TRACK2"}]}`,
+		`not json`,
+	}
+	call := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, responses[call])
+		call++
+	}))
+	defer server.Close()
+
+	client := &FaireClient{BaseURL: server.URL}
+	created, err := client.AddShipment(context.Background(), ShipmentPayload{OrderID: "bo_abc", TrackingCode: "TRACK1"}, "dummy-token")
+	if err != nil || len(created) != 1 || created[0].ID != "shp_1" || created[0].CreatedAt.IsZero() {
+		t.Errorf("AddShipment() with a list response = %+v, %v, want shp_1 with its timestamp", created, err)
+	}
+	created, err = client.AddShipment(context.Background(), ShipmentPayload{OrderID: "bo_abc", TrackingCode: "TRACK2"}, "dummy-token")
+	if err != nil || len(created) != 1 || created[0].ID != "shp_2" || created[0].Carrier != "FEDEX" {
+		t.Errorf("AddShipment() with a wrapped response = %+v, %v, want shp_2", created, err)
+	}
+	created, err = client.AddShipment(context.Background(), ShipmentPayload{OrderID: "bo_abc", TrackingCode: "TRACK3"}, "dummy-token")
+	if err != nil || len(created) != 0 {
+		t.Errorf("AddShipment() with an unrecognized response = %+v, %v, want no shipments and no error", created, err)
+	}
+}
+
+func TestMatchCreatedShipments(t *testing.T) {
+	payloads := []ShipmentPayload{{TrackingCode: "A"}, {TrackingCode: "b"}, {TrackingCode: "C"}}
+	created := []OrderShipment{{ID: "2", TrackingCode: "B"}, {ID: "1", TrackingCode: "a"}, {ID: "3", TrackingCode: "other"}}
+	matches := matchCreatedShipments(payloads, created)
+	var ids []string
+	for _, match := range matches {
+		if match == nil {
+			ids = append(ids, "")
+			continue
+		}
+		ids = append(ids, match.ID)
+	}
+	if want := []string{"1", "2", ""}; strings.Join(ids, ",") != strings.Join(want, ",") {
+		t.Errorf("matched IDs = %v, want %v", ids, want)
+	}
+	if matches := matchCreatedShipments(payloads, created[:1]); matches[1] == nil || matches[0] != nil || matches[2] != nil {
+		t.Errorf("partial response matches = %v, want only the payload with tracking code b", matches)
+	}
+
+	// One shipment per payload, in order, but the second shipment's tracking code belongs to neither payload.
+	pair := []ShipmentPayload{{TrackingCode: "X"}, {TrackingCode: "Y"}}
+	matches = matchCreatedShipments(pair, []OrderShipment{{ID: "4", TrackingCode: "x"}, {ID: "5", TrackingCode: "Z"}})
+	if matches[0] == nil || matches[0].ID != "4" || matches[1] != nil {
+		t.Errorf("mismatched tracking code matches = %v, want only X matched", matches)
+	}
+	// Shipments without tracking codes are still paired by position.
+	matches = matchCreatedShipments(pair, []OrderShipment{{ID: "6"}, {ID: "7"}})
+	if matches[0] == nil || matches[0].ID != "6" || matches[1] == nil || matches[1].ID != "7" {
+		t.Errorf("untracked response matches = %v, want 6 and 7 by position", matches)
+	}
+}
+
+func TestAddShipmentReturnsAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"type":"INVALID_CARRIER","message":"Carrier FOO is not supported"}`))
+	}))
+	defer server.Close()
+
+	client := &FaireClient{BaseURL: server.URL}
+	_, err := client.AddShipment(context.Background(), ShipmentPayload{OrderID: "bo_abc"}, "dummy-token")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("AddShipment() error = %v, want an *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Method != http.MethodPost || apiErr.Path != "/orders/bo_abc/shipments" {
+		t.Errorf("APIError request = %d %s %s, want 400 POST /orders/bo_abc/shipments", apiErr.StatusCode, apiErr.Method, apiErr.Path)
+	}
+	if apiErr.Code != "INVALID_CARRIER" || apiErr.Message != "Carrier FOO is not supported" || apiErr.Retryable {
+		t.Errorf("APIError = %+v, want Faire's code and message and not retryable", apiErr)
+	}
+	if want := "faire API error (400 Bad Request) on POST /orders/bo_abc/shipments: INVALID_CARRIER: Carrier FOO is not supported"; err.Error() != want {
+		t.Errorf("AddShipment() error = %q, want %q", err.Error(), want)
+	}
+}
@@ -40,3 +40,17 @@ func FormatOrders(orders []Order) string {
 	}
 	return b.String()
 }
+
+// FormatOrderProgress returns a one-line description of an order retrieval's progress.
+func FormatOrderProgress(p OrderProgress) string {
+	switch {
+	case p.Total > 0 && p.Done == 0:
+		return fmt.Sprintf("Looking up %d orders...", p.Total)
+	case p.Total > 0:
+		return fmt.Sprintf("Retrieved %d of %d orders (last: %s)", p.Done, p.Total, p.OrderIdentifier)
+	case p.Page > 0:
+		return fmt.Sprintf("Retrieved page %d: %d matching orders so far", p.Page, p.Retrieved)
+	default:
+		return "Requesting the first page of orders..."
+	}
+}
@@ -0,0 +1,290 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// RunOutcome is the recorded result of one shipment in a ShipmentRun.
+type RunOutcome string
+
+const (
+	RunOutcomeProcessed      RunOutcome = "processed"
+	RunOutcomeFailed         RunOutcome = "failed"
+	RunOutcomeAlreadyPresent RunOutcome = "already_present"
+	RunOutcomeDuplicate      RunOutcome = "duplicate"
+	// RunOutcomeUnconfirmed marks failures that were in flight when the run was canceled, so Faire may have created them.
+	RunOutcomeUnconfirmed RunOutcome = "unconfirmed"
+)
+
+// failedOutcome returns the outcome recorded for a failed payload.
+func failedOutcome(payload ShipmentPayload) RunOutcome {
+	if payload.FailureKind == FailureOutcomeUnknown {
+		return RunOutcomeUnconfirmed
+	}
+	return RunOutcomeFailed
+}
+
+// ShipmentRun is the audit record of one shipment-processing run.
+type ShipmentRun struct {
+	ID          string    `json:"id"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	InputFile   string    `json:"input_file"`
+	InputSHA256 string    `json:"input_sha256"` // Empty when the input could not be read for hashing.
+	Operator    string    `json:"operator"`
+	Mock        bool      `json:"mock"`
+	// Error is set when the run stopped before submitting, for example because the file could not be parsed.
+	Error string `json:"error,omitempty"`
+	// Canceled is set when the operator canceled the run before every shipment was sent.
+	Canceled    bool               `json:"canceled,omitempty"`
+	Shipments   []RecordedShipment `json:"shipments"`
+	SkippedRows []SkippedRow       `json:"skipped_rows"`
+}
+
+// RecordedShipment is a submitted payload with its outcome; failures keep Faire's message in ErrorMsg, and
+// duplicates name the row they repeat.
+type RecordedShipment struct {
+	ShipmentPayload
+	Outcome RunOutcome `json:"outcome"`
+}
+
+// NewShipmentRun builds the audit record for processing inputPath between startedAt and now.
+// runErr is the error returned by ProcessShipmentsWithOptions, if any.
+func NewShipmentRun(inputPath string, mock bool, startedAt time.Time, results ShipmentResults, runErr error) ShipmentRun {
+	run := ShipmentRun{
+		ID:          startedAt.UTC().Format("20060102T150405.000000000Z"),
+		StartedAt:   startedAt,
+		FinishedAt:  time.Now(),
+		InputFile:   inputPath,
+		Operator:    currentOperator(),
+		Mock:        mock,
+		Canceled:    results.Canceled,
+		SkippedRows: results.SkippedRows,
+	}
+	if absPath, err := filepath.Abs(inputPath); err == nil {
+		run.InputFile = absPath
+	}
+	if sum, err := fileSHA256(inputPath); err == nil {
+		run.InputSHA256 = sum
+	}
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+	add := func(payloads []ShipmentPayload, outcome RunOutcome) {
+		for _, payload := range payloads {
+			run.Shipments = append(run.Shipments, RecordedShipment{ShipmentPayload: payload, Outcome: outcome})
+		}
+	}
+	add(results.Processed, RunOutcomeProcessed)
+	for _, payload := range results.Failed {
+		run.Shipments = append(run.Shipments, RecordedShipment{ShipmentPayload: payload, Outcome: failedOutcome(payload)})
+	}
+	add(results.AlreadyPresent, RunOutcomeAlreadyPresent)
+	add(results.Duplicates, RunOutcomeDuplicate)
+	return run
+}
+
+// ShipmentHistory stores ShipmentRun records as JSON lines in a local file.
+type ShipmentHistory struct {
+	Path string
+}
+
+// historyMu serializes appends from concurrent runs within the process.
+var historyMu sync.Mutex
+
+// DefaultShipmentHistory returns the history at FAIRE_HISTORY_PATH, or shipment_history.jsonl in the user's config directory.
+func DefaultShipmentHistory() (ShipmentHistory, error) {
+	_ = godotenv.Load()
+	if path := strings.TrimSpace(os.Getenv("FAIRE_HISTORY_PATH")); path != "" {
+		return ShipmentHistory{Path: path}, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ShipmentHistory{}, fmt.Errorf("locate shipment history: %w", err)
+	}
+	return ShipmentHistory{Path: filepath.Join(dir, "bsc-faire", "shipment_history.jsonl")}, nil
+}
+
+// Record appends run to the history, creating the file and its directory if needed.
+func (h ShipmentHistory) Record(run ShipmentRun) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("encode shipment run: %w", err)
+	}
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(h.Path), 0o755); err != nil {
+		return fmt.Errorf("create shipment history directory: %w", err)
+	}
+	f, err := os.OpenFile(h.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open shipment history: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("write shipment history: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write shipment history: %w", err)
+	}
+	return nil
+}
+
+// maxHistoryLine is the longest history line Runs reads; a run with thousands of shipments fits comfortably.
+const maxHistoryLine = 64 << 20
+
+// Runs returns every recorded run, newest first, and how many lines were skipped because they could not be parsed,
+// such as one cut short when the application was killed mid-write. A missing history file has no runs.
+func (h ShipmentHistory) Runs() ([]ShipmentRun, int, error) {
+	f, err := os.Open(h.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("open shipment history: %w", err)
+	}
+	defer f.Close()
+
+	var runs []ShipmentRun
+	skipped := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxHistoryLine)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var run ShipmentRun
+		if err := json.Unmarshal(line, &run); err != nil {
+			skipped++
+			continue
+		}
+		runs = append(runs, run)
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	if err := scanner.Err(); err != nil {
+		return runs, skipped, fmt.Errorf("read shipment history: %w", err)
+	}
+	return runs, skipped, nil
+}
+
+// SearchShipmentRuns returns the runs containing a shipment whose order display ID, order ID or tracking code
+// contains query, keeping only the matching shipments. An empty query returns runs unchanged.
+func SearchShipmentRuns(runs []ShipmentRun, query string) []ShipmentRun {
+	query = strings.ToUpper(strings.TrimSpace(query))
+	if query == "" {
+		return runs
+	}
+	var matches []ShipmentRun
+	for _, run := range runs {
+		var shipments []RecordedShipment
+		for _, shipment := range run.Shipments {
+			if strings.Contains(OrderIDToDisplayID(shipment.OrderID), query) ||
+				strings.Contains(strings.ToUpper(shipment.OrderID), query) ||
+				strings.Contains(normalizeTrackingCode(shipment.TrackingCode), normalizeTrackingCode(query)) {
+				shipments = append(shipments, shipment)
+			}
+		}
+		if len(shipments) > 0 {
+			run.Shipments = shipments
+			matches = append(matches, run)
+		}
+	}
+	return matches
+}
+
+// FormatShipmentRuns returns a readable listing of runs and their shipments for the history view and terminal output.
+func FormatShipmentRuns(runs []ShipmentRun) string {
+	if len(runs) == 0 {
+		return "No recorded shipment runs.\n"
+	}
+	var b strings.Builder
+	for i, run := range runs {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		mode := "live"
+		if run.Mock {
+			mode = "mock"
+		}
+		fmt.Fprintf(&b, "%s  %s  %s (%s)\n", run.StartedAt.Local().Format("2006-01-02 15:04:05"), run.Operator, filepath.Base(run.InputFile), mode)
+		if run.InputSHA256 != "" {
+			fmt.Fprintf(&b, "  SHA-256: %s\n", run.InputSHA256)
+		}
+		if run.Error != "" {
+			fmt.Fprintf(&b, "  Error: %s\n", run.Error)
+		}
+		if run.Canceled {
+			b.WriteString("  Canceled before every shipment was sent")
+			if n := countOutcome(run.Shipments, RunOutcomeUnconfirmed); n > 0 {
+				fmt.Fprintf(&b, "; %s in flight may have been created", countNoun(n, "shipment", "shipments"))
+			}
+			b.WriteString("\n")
+		}
+		for _, shipment := range run.Shipments {
+			fmt.Fprintf(&b, "  %-15s %s  %s  %s %s  $%.2f",
+				shipment.Outcome, OrderIDToDisplayID(shipment.OrderID), shipment.SaleSource,
+				shipment.Carrier, shipment.TrackingCode, float64(shipment.MakerCostCents)/100)
+			if id := createdShipmentID(shipment.ShipmentPayload); id != "" {
+				fmt.Fprintf(&b, "  shipment %s", id)
+			}
+			if shipment.ErrorMsg != "" {
+				fmt.Fprintf(&b, "  error: %s", shipment.ErrorMsg)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// countOutcome returns how many of shipments have outcome.
+func countOutcome(shipments []RecordedShipment, outcome RunOutcome) int {
+	n := 0
+	for _, shipment := range shipments {
+		if shipment.Outcome == outcome {
+			n++
+		}
+	}
+	return n
+}
+
+// currentOperator returns FAIRE_OPERATOR, or the name of the logged-in user.
+func currentOperator() string {
+	if operator := strings.TrimSpace(os.Getenv("FAIRE_OPERATOR")); operator != "" {
+		return operator
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "unknown"
+}
+
+// fileSHA256 returns the hex SHA-256 digest of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShipmentHistory(t *testing.T) {
+	t.Setenv("FAIRE_OPERATOR", "accounting")
+	dir := t.TempDir()
+	input := filepath.Join(dir, "shipments.csv")
+	if err := os.WriteFile(input, []byte("abc"), 0o644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+	history := ShipmentHistory{Path: filepath.Join(dir, "history", "runs.jsonl")}
+
+	if runs, skipped, err := history.Runs(); err != nil || len(runs) != 0 || skipped != 0 {
+		t.Fatalf("Runs() on a missing file = %v, %d, %v, want no runs", runs, skipped, err)
+	}
+
+	older := NewShipmentRun(input, true, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), ShipmentResults{
+		Processed: []ShipmentPayload{{OrderID: "bo_order1", TrackingCode: "1Z999", SaleSource: "BSC"}},
+		Failed:    []ShipmentPayload{{OrderID: "bo_order2", TrackingCode: "TRACK2", ErrorMsg: "boom"}},
+	}, nil)
+	newer := NewShipmentRun(input, false, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), ShipmentResults{}, errors.New("bad file"))
+	for _, run := range []ShipmentRun{older, newer} {
+		if err := history.Record(run); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	runs, skipped, err := history.Runs()
+	if err != nil || skipped != 0 {
+		t.Fatalf("Runs() = %d skipped, error %v, want every line read", skipped, err)
+	}
+	if len(runs) != 2 || runs[0].Error != "bad file" || runs[1].Operator != "accounting" || !runs[1].Mock {
+		t.Fatalf("runs = %+v, want the newer failed run first and the older mock run second", runs)
+	}
+	if want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; runs[1].InputSHA256 != want {
+		t.Errorf("InputSHA256 = %q, want %q", runs[1].InputSHA256, want)
+	}
+	if got := runs[1].Shipments; len(got) != 2 || got[0].Outcome != RunOutcomeProcessed || got[1].Outcome != RunOutcomeFailed || got[1].ErrorMsg != "boom" {
+		t.Errorf("shipments = %+v, want one processed and one failed with its error", got)
+	}
+
+	byTracking := SearchShipmentRuns(runs, "1z999")
+	if len(byTracking) != 1 || len(byTracking[0].Shipments) != 1 || byTracking[0].Shipments[0].OrderID != "bo_order1" {
+		t.Errorf("SearchShipmentRuns(tracking) = %+v, want the order1 shipment only", byTracking)
+	}
+	byOrder := SearchShipmentRuns(runs, "ORDER2")
+	if len(byOrder) != 1 || byOrder[0].Shipments[0].TrackingCode != "TRACK2" {
+		t.Errorf("SearchShipmentRuns(order) = %+v, want the order2 shipment", byOrder)
+	}
+	if text := FormatShipmentRuns(byOrder); !strings.Contains(text, "failed") || !strings.Contains(text, "error: boom") {
+		t.Errorf("FormatShipmentRuns() = %q, want the failure and its error", text)
+	}
+}
+
+func TestNewShipmentRun_MarksInFlightFailuresUnconfirmed(t *testing.T) {
+	run := NewShipmentRun(filepath.Join(t.TempDir(), "shipments.csv"), true, time.Now(), ShipmentResults{
+		Failed: []ShipmentPayload{
+			{OrderID: "bo_order1", TrackingCode: "TRACK1", ErrorMsg: "context canceled", FailureKind: FailureOutcomeUnknown},
+			{OrderID: "bo_order2", TrackingCode: "TRACK2", ErrorMsg: "not sent: context canceled", FailureKind: FailureNotSent},
+		},
+		Canceled: true,
+	}, nil)
+
+	if got := run.Shipments; len(got) != 2 || got[0].Outcome != RunOutcomeUnconfirmed || got[1].Outcome != RunOutcomeFailed {
+		t.Fatalf("shipments = %+v, want the in-flight shipment unconfirmed and the unsent one failed", got)
+	}
+	if text := FormatShipmentRuns([]ShipmentRun{run}); !strings.Contains(text, "1 shipment in flight may have been created") {
+		t.Errorf("FormatShipmentRuns() = %q, want the in-flight shipment counted", text)
+	}
+}
+
+func TestShipmentHistoryRuns_SkipsCorruptLines(t *testing.T) {
+	history := ShipmentHistory{Path: filepath.Join(t.TempDir(), "runs.jsonl")}
+	content := `{"id":"a","started_at":"2026-01-01T00:00:00Z"}
+{"id":"b","started_at":"2026-03-01T00:00:00Z","shipm
+{"id":"c","started_at":"2026-02-01T00:00:00Z"}
+
+not json
+{"id":"d","started_at":"2026-04-01T00:00:00Z"}
+`
+	if err := os.WriteFile(history.Path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write history: %v", err)
+	}
+
+	runs, skipped, err := history.Runs()
+	if err != nil {
+		t.Fatalf("Runs() error = %v", err)
+	}
+	if skipped != 2 {
+		t.Errorf("skipped = %d, want 2", skipped)
+	}
+	var ids []string
+	for _, run := range runs {
+		ids = append(ids, run.ID)
+	}
+	if got := strings.Join(ids, ","); got != "d,c,a" {
+		t.Errorf("run IDs = %s, want the readable runs newest first: d,c,a", got)
+	}
+}
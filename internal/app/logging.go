@@ -0,0 +1,236 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+const (
+	// defaultLogMaxBytes is the size at which the log file is rotated.
+	defaultLogMaxBytes = 5 << 20
+	// defaultLogBackups is the number of rotated log files kept beside the current one.
+	defaultLogBackups = 3
+	// maxLoggedBodyBytes limits how much of each request or response body is logged.
+	maxLoggedBodyBytes = 64 << 10
+)
+
+// redactedHeaders lists the headers whose values are replaced before headers are logged.
+var redactedHeaders = []string{"X-Faire-Access-Token", "Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// LogConfig describes where FaireClient's request log is written and how much it records.
+type LogConfig struct {
+	// Path is the log file; rotated files are kept beside it as Path.1, Path.2, and so on.
+	Path string
+	// Level is the lowest level recorded. Info records every request; Debug adds headers and bodies.
+	Level slog.Level
+	// Disabled turns logging off.
+	Disabled bool
+}
+
+// LogConfigFromEnv reads FAIRE_LOG_PATH and FAIRE_LOG_LEVEL. Logging is off unless FAIRE_LOG_LEVEL is debug, info,
+// warn, or error, or FAIRE_LOG_PATH is set, which logs at the info level. The log defaults to faire.log in the
+// user's config directory.
+func LogConfigFromEnv() (LogConfig, error) {
+	_ = godotenv.Load()
+	config := LogConfig{Path: strings.TrimSpace(os.Getenv("FAIRE_LOG_PATH")), Level: slog.LevelInfo}
+	switch level := strings.ToLower(strings.TrimSpace(os.Getenv("FAIRE_LOG_LEVEL"))); level {
+	case "":
+		config.Disabled = config.Path == ""
+	case "info":
+	case "debug":
+		config.Level = slog.LevelDebug
+	case "warn":
+		config.Level = slog.LevelWarn
+	case "error":
+		config.Level = slog.LevelError
+	case "off":
+		config.Disabled = true
+	default:
+		return config, fmt.Errorf("FAIRE_LOG_LEVEL %q must be debug, info, warn, error, or off", level)
+	}
+	if config.Path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return config, fmt.Errorf("locate log file: %w", err)
+		}
+		config.Path = filepath.Join(dir, "bsc-faire", "faire.log")
+	}
+	return config, nil
+}
+
+// NewLogger returns a logger that writes text records to config's rotating log file, or nil when logging is disabled.
+func NewLogger(config LogConfig) (*slog.Logger, error) {
+	if config.Disabled {
+		return nil, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("create log directory: %w", err)
+	}
+	file := &RotatingFile{Path: config.Path, MaxBytes: defaultLogMaxBytes, MaxBackups: defaultLogBackups}
+	return slog.New(slog.NewTextHandler(file, &slog.HandlerOptions{Level: config.Level})), nil
+}
+
+var (
+	defaultLoggerOnce sync.Once
+	defaultLogger     *slog.Logger
+	defaultLoggerErr  error
+)
+
+// DefaultLogger returns the logger configured by LogConfigFromEnv. It is created on first use and shared by every
+// client in the process, so concurrent requests rotate one file.
+func DefaultLogger() (*slog.Logger, error) {
+	defaultLoggerOnce.Do(func() {
+		config, err := LogConfigFromEnv()
+		if err != nil {
+			defaultLoggerErr = err
+			return
+		}
+		defaultLogger, defaultLoggerErr = NewLogger(config)
+	})
+	return defaultLogger, defaultLoggerErr
+}
+
+// RotatingFile is an io.Writer that appends to Path and, once a write would take it past MaxBytes, renames it to
+// Path.1, shifting older files up to Path.MaxBackups and removing the oldest. It is safe for concurrent use.
+type RotatingFile struct {
+	Path       string
+	MaxBytes   int64
+	MaxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Write appends p to the file, rotating first when needed.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if r.MaxBytes > 0 && r.size > 0 && r.size+int64(len(p)) > r.MaxBytes {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file; a later Write reopens it.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// open opens Path for appending and records its current size.
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("open log file: %w", err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// rotate shifts the backups, moves the current file to Path.1, and opens a new empty file.
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("rotate log file: %w", err)
+	}
+	r.file = nil
+	if r.MaxBackups < 1 {
+		if err := os.Remove(r.Path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("rotate log file: %w", err)
+		}
+		return r.open()
+	}
+	_ = os.Remove(fmt.Sprintf("%s.%d", r.Path, r.MaxBackups))
+	for i := r.MaxBackups - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", r.Path, i), fmt.Sprintf("%s.%d", r.Path, i+1))
+	}
+	if err := os.Rename(r.Path, r.Path+".1"); err != nil {
+		return fmt.Errorf("rotate log file: %w", err)
+	}
+	return r.open()
+}
+
+// redactHeaders returns a copy of header with credentials replaced by "REDACTED".
+func redactHeaders(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range redactedHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, "REDACTED")
+		}
+	}
+	return redacted
+}
+
+// truncateLogBody returns body as text, shortened to maxLoggedBodyBytes.
+func truncateLogBody(body []byte) string {
+	if len(body) <= maxLoggedBodyBytes {
+		return string(body)
+	}
+	return fmt.Sprintf("%s... (%d bytes in total)", body[:maxLoggedBodyBytes], len(body))
+}
+
+// ReadLogTail returns up to maxBytes from the end of the log file at path, starting at a line boundary.
+// A missing file yields an empty string.
+func ReadLogTail(path string, maxBytes int64) (string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("open log file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("read log file: %w", err)
+	}
+	offset := info.Size() - maxBytes
+	if offset < 0 {
+		offset = 0
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return "", fmt.Errorf("read log file: %w", err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("read log file: %w", err)
+	}
+	text := string(data)
+	if offset > 0 {
+		if newline := strings.IndexByte(text, '\n'); newline >= 0 {
+			text = text[newline+1:]
+		}
+	}
+	return text, nil
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFaireClientLogsRequestsWithoutTokens confirms a failed request is logged with Faire's raw response and
+// without the access token.
+func TestFaireClientLogsRequestsWithoutTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"type":"NOT_FOUND","message":"Order not found"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client := &FaireClient{
+		BaseURL: server.URL,
+		Logger:  slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+	if _, err := client.AddShipment(context.Background(), ShipmentPayload{OrderID: "bo_abc", TrackingCode: "TRACK1"}, "secret-token"); err == nil {
+		t.Fatal("AddShipment() returned no error for a 404 response")
+	}
+
+	logged := buf.String()
+	for _, want := range []string{"level=WARN", "method=POST", "/orders/bo_abc/shipments", "status=404", "latency=", "Order not found", "TRACK1", "REDACTED"} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("log does not contain %q:\n%s", want, logged)
+		}
+	}
+	if strings.Contains(logged, "secret-token") {
+		t.Errorf("log contains the access token:\n%s", logged)
+	}
+}
+
+// TestLogConfigFromEnv confirms logging is off until FAIRE_LOG_LEVEL or FAIRE_LOG_PATH turns it on.
+func TestLogConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		level, path  string
+		wantDisabled bool
+		wantLevel    slog.Level
+	}{
+		{level: "", path: "", wantDisabled: true, wantLevel: slog.LevelInfo},
+		{level: "off", path: "faire.log", wantDisabled: true, wantLevel: slog.LevelInfo},
+		{level: "", path: "faire.log", wantLevel: slog.LevelInfo},
+		{level: "info", path: "", wantLevel: slog.LevelInfo},
+		{level: "DEBUG", path: "", wantLevel: slog.LevelDebug},
+	}
+	for _, tt := range tests {
+		t.Setenv("FAIRE_LOG_LEVEL", tt.level)
+		t.Setenv("FAIRE_LOG_PATH", tt.path)
+		config, err := LogConfigFromEnv()
+		if err != nil {
+			t.Fatalf("LogConfigFromEnv() with level %q and path %q error = %v", tt.level, tt.path, err)
+		}
+		if config.Disabled != tt.wantDisabled || config.Level != tt.wantLevel {
+			t.Errorf("LogConfigFromEnv() with level %q and path %q = %+v, want disabled %t at %v",
+				tt.level, tt.path, config, tt.wantDisabled, tt.wantLevel)
+		}
+	}
+
+	t.Setenv("FAIRE_LOG_LEVEL", "verbose")
+	if _, err := LogConfigFromEnv(); err == nil {
+		t.Error("LogConfigFromEnv() accepted an unknown level")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "faire.log")
+	file := &RotatingFile{Path: path, MaxBytes: 10, MaxBackups: 2}
+	defer file.Close()
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatalf("Write(%q) error = %v", line, err)
+		}
+	}
+
+	for suffix, want := range map[string]string{"": "fourth\n", ".1": "third\n", ".2": "second\n"} {
+		data, err := os.ReadFile(path + suffix)
+		if err != nil || string(data) != want {
+			t.Errorf("faire.log%s = %q, %v; want %q", suffix, data, err, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("faire.log.3 exists, want only two backups")
+	}
+
+	tail, err := ReadLogTail(path+".1", 4)
+	if err != nil || tail != "" {
+		t.Errorf("ReadLogTail() = %q, %v; want the partial line dropped", tail, err)
+	}
+}
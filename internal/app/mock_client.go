@@ -1,18 +1,35 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"strings"
+	"sync"
 	"time"
 )
 
 // MockFaireClient implements FaireClientInterface for local testing and development.
+// It is safe for concurrent use.
 type MockFaireClient struct {
-	CallCount  int
-	FailOnCall map[int]bool // Map of one-based shipment call indices that should fail.
-	Orders     []Order      // Orders returned when a test does not supply its own set.
+	mu        sync.Mutex
+	CallCount int
+	// FailOnCall holds the one-based numbers of the shipment requests that should fail. Requests are numbered in plan
+	// order because shipment runs submit sequentially while it is set.
+	FailOnCall map[int]bool
+	Orders     []Order // Orders returned when a test does not supply its own set.
+	// AutoCreateOrders makes GetOrderByID return an empty NEW order for unknown identifiers,
+	// so any shipment CSV can be processed against the mock.
+	AutoCreateOrders bool
+
+	shipmentCalls    int                        // Number of AddShipments calls, used for FailOnCall.
+	shipments        map[string][]OrderShipment // Successfully added shipments keyed by lowercase order ID.
+	createdShipments int                        // Number of shipments created, used to assign mock shipment IDs.
 }
 
+// mockLatency simulates network and processing delay so the GUI behaves as it does against the live API.
+const mockLatency = 300 * time.Millisecond
+
 // MockOrders is a shared set of mock orders for testing/demo
 var MockOrders = []Order{
 	{
@@ -41,14 +58,59 @@ var MockOrders = []Order{
 	},
 }
 
-// AddShipment simulates adding a shipment and fails configured calls.
-func (m *MockFaireClient) AddShipment(payload ShipmentPayload, apiToken string) error {
-	time.Sleep(300 * time.Millisecond) // Simulate network/processing delay
-	m.CallCount++
-	if m.FailOnCall != nil && m.FailOnCall[m.CallCount] {
-		return &MockError{"simulated failure"}
+// AddShipments simulates adding a batch of shipments to one order, fails configured calls, and returns the created shipments.
+func (m *MockFaireClient) AddShipments(ctx context.Context, payloads []ShipmentPayload, apiToken string) ([]OrderShipment, error) {
+	m.nextCall()
+	if err := sleepContext(ctx, mockLatency); err != nil {
+		return nil, err
+	}
+	orderID, err := shipmentsOrderID(payloads)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shipmentCalls++
+	if m.FailOnCall != nil && m.FailOnCall[m.shipmentCalls] {
+		return nil, &MockError{"simulated failure"}
 	}
-	return nil
+	if m.shipments == nil {
+		m.shipments = make(map[string][]OrderShipment)
+	}
+	key := strings.ToLower(orderID)
+	now := time.Now().UTC()
+	created := make([]OrderShipment, 0, len(payloads))
+	for _, payload := range payloads {
+		m.createdShipments++
+		shipment := OrderShipment{
+			ID:             fmt.Sprintf("mock_shp_%d", m.createdShipments),
+			OrderID:        orderID,
+			MakerCostCents: payload.MakerCostCents,
+			Carrier:        strings.ToUpper(strings.TrimSpace(payload.Carrier)),
+			TrackingCode:   payload.TrackingCode,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+			ShippingType:   payload.ShippingType,
+		}
+		m.shipments[key] = append(m.shipments[key], shipment)
+		created = append(created, shipment)
+	}
+	return created, nil
+}
+
+// submitsSequentially reports whether shipment runs must send requests one at a time, so FailOnCall picks the same
+// requests on every run instead of whichever a concurrent worker happens to send first.
+func (m *MockFaireClient) submitsSequentially() bool {
+	return len(m.FailOnCall) > 0
+}
+
+// nextCall increments CallCount and returns the one-based number of the current call.
+func (m *MockFaireClient) nextCall() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.CallCount++
+	return m.CallCount
 }
 
 // MockError is an error returned by MockFaireClient when simulating a failed API call.
@@ -62,9 +124,11 @@ func (e *MockError) Error() string {
 }
 
 // GetAllOrders returns mock orders as JSON after applying the supplied inverse state filter.
-func (m *MockFaireClient) GetAllOrders(apiToken string, limit int, page int, excludedStates string) ([]byte, error) {
-	time.Sleep(300 * time.Millisecond) // Match the asynchronous timing of the live client during manual testing.
-	m.CallCount++
+func (m *MockFaireClient) GetAllOrders(ctx context.Context, apiToken string, limit int, page int, excludedStates string) ([]byte, error) {
+	m.nextCall()
+	if err := sleepContext(ctx, mockLatency); err != nil {
+		return nil, err
+	}
 	orders := m.Orders
 	if orders == nil {
 		orders = MockOrders
@@ -86,17 +150,39 @@ func (m *MockFaireClient) GetAllOrders(apiToken string, limit int, page int, exc
 }
 
 // GetOrderByID returns a single mock order by display ID or internal mock ID as JSON.
-func (m *MockFaireClient) GetOrderByID(orderIdentifier string, apiToken string) ([]byte, error) {
-	time.Sleep(300 * time.Millisecond) // Simulate network/processing delay
-	m.CallCount++
+func (m *MockFaireClient) GetOrderByID(ctx context.Context, orderIdentifier string, apiToken string) ([]byte, error) {
+	m.nextCall()
+	if err := sleepContext(ctx, mockLatency); err != nil {
+		return nil, err
+	}
 	orders := m.Orders
 	if orders == nil {
 		orders = MockOrders
 	}
+	orderID := OrderIdentifierToOrderID(orderIdentifier)
 	for _, order := range orders {
-		if strings.EqualFold(order.DisplayID, orderIdentifier) || strings.EqualFold(order.ID, orderIdentifier) {
-			return json.Marshal(order)
+		if strings.EqualFold(order.DisplayID, orderIdentifier) || strings.EqualFold(order.ID, orderIdentifier) ||
+			DisplayIDToOrderID(order.DisplayID) == orderID {
+			return json.Marshal(m.withShipments(order))
 		}
 	}
+	if m.AutoCreateOrders && orderID != "" {
+		return json.Marshal(m.withShipments(Order{ID: orderID, DisplayID: OrderIDToDisplayID(orderID), State: OrderStateNew}))
+	}
 	return nil, &MockError{"order not found"}
 }
+
+// withShipments returns order with the shipments previously added to it through this client.
+func (m *MockFaireClient) withShipments(order Order) Order {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	added := m.shipments[strings.ToLower(order.ID)]
+	if displayOrderID := DisplayIDToOrderID(order.DisplayID); displayOrderID != strings.ToLower(order.ID) {
+		added = append(added[:len(added):len(added)], m.shipments[displayOrderID]...)
+	}
+	if len(added) == 0 {
+		return order
+	}
+	order.Shipments = append(append([]OrderShipment(nil), order.Shipments...), added...)
+	return order
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxMoneyDigits bounds the whole-dollar digits accepted by ParseMoneyCents so the result cannot overflow an int.
+const maxMoneyDigits = 15
+
+// ParseMoneyCents converts a dollar amount such as "19.99", "$1,234.50" or "USD 7" to cents using exact decimal arithmetic.
+// Blank values are zero, fractions of a cent are rounded half up, and negative amounts are rejected.
+func ParseMoneyCents(value string) (int, error) {
+	s := strings.TrimSpace(value)
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") || strings.HasPrefix(s, "-") {
+		return 0, fmt.Errorf("negative amount")
+	}
+	s = strings.TrimPrefix(s, "+")
+	for _, symbol := range []string{"USD", "US$", "$"} {
+		if len(s) >= len(symbol) && strings.EqualFold(s[:len(symbol)], symbol) {
+			s = s[len(symbol):]
+		}
+		if len(s) >= len(symbol) && strings.EqualFold(s[len(s)-len(symbol):], symbol) {
+			s = s[:len(s)-len(symbol)]
+		}
+		s = strings.TrimSpace(s)
+	}
+	if s == "" {
+		return 0, nil
+	}
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	whole, err := stripThousandsSeparators(whole)
+	if err != nil {
+		return 0, err
+	}
+	if whole == "" && fraction == "" {
+		return 0, fmt.Errorf("no digits")
+	}
+	if !isDigits(whole) || !isDigits(fraction) {
+		return 0, fmt.Errorf("not a dollar amount")
+	}
+	if len(whole) > maxMoneyDigits {
+		return 0, fmt.Errorf("amount is too large")
+	}
+
+	cents := 0
+	for _, d := range whole {
+		cents = cents*10 + int(d-'0')
+	}
+	for i := 0; i < 2; i++ {
+		cents *= 10
+		if i < len(fraction) {
+			cents += int(fraction[i] - '0')
+		}
+	}
+	if len(fraction) > 2 && fraction[2] >= '5' {
+		cents++
+	}
+	return cents, nil
+}
+
+// FormatCents renders cents as a plain dollar amount such as "1234.50".
+func FormatCents(cents int) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// stripThousandsSeparators removes commas from whole, rejecting commas that do not separate groups of three digits.
+func stripThousandsSeparators(whole string) (string, error) {
+	if !strings.Contains(whole, ",") {
+		return whole, nil
+	}
+	groups := strings.Split(whole, ",")
+	for i, group := range groups {
+		if (i == 0 && (len(group) == 0 || len(group) > 3)) || (i > 0 && len(group) != 3) {
+			return "", fmt.Errorf("misplaced thousands separator")
+		}
+	}
+	return strings.Join(groups, ""), nil
+}
+
+// isDigits reports whether s contains only ASCII digits.
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package app
+
+import "testing"
+
+func TestParseMoneyCents(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{input: "19.99", want: 1999},
+		{input: "0.29", want: 29},
+		{input: "10", want: 1000},
+		{input: "10.5", want: 1050},
+		{input: ".75", want: 75},
+		{input: "$1,234.50", want: 123450},
+		{input: " USD 7.00 ", want: 700},
+		{input: "12.34 USD", want: 1234},
+		{input: "", want: 0},
+		{input: "  ", want: 0},
+		{input: "$", want: 0},
+		{input: "2.005", want: 201},
+		{input: "2.0049", want: 200},
+		{input: "1,000,000", want: 100000000},
+		{input: "ABC", wantErr: true},
+		{input: "-5.00", wantErr: true},
+		{input: "(5.00)", wantErr: true},
+		{input: "1,23.00", wantErr: true},
+		{input: "1.2.3", wantErr: true},
+		{input: "1e3", wantErr: true},
+		{input: "1234567890123456", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoneyCents(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseMoneyCents(%q) = %d, want an error", tt.input, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseMoneyCents(%q) = %d, %v, want %d", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestFormatCents(t *testing.T) {
+	for cents, want := range map[int]string{0: "0.00", 5: "0.05", 1999: "19.99", 123450: "1234.50", -250: "-2.50"} {
+		if got := FormatCents(cents); got != want {
+			t.Errorf("FormatCents(%d) = %q, want %q", cents, got, want)
+		}
+	}
+}
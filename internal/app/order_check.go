@@ -0,0 +1,52 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OrderStateCheck selects what happens to shipments for orders that should not be shipped, such as canceled
+// orders or orders the retailer has asked to cancel.
+type OrderStateCheck string
+
+const (
+	// OrderStateCheckOff posts shipments without looking at the order's state.
+	OrderStateCheckOff OrderStateCheck = ""
+	// OrderStateCheckWarn posts shipments but reports a warning for each problem order.
+	OrderStateCheckWarn OrderStateCheck = "warn"
+	// OrderStateCheckBlock fails shipments for problem orders without posting them.
+	OrderStateCheckBlock OrderStateCheck = "block"
+)
+
+// unshippableOrderStates lists Faire states in which adding a shipment to an order is a mistake.
+var unshippableOrderStates = []string{
+	"CANCELED",
+	"DELIVERED",
+	"RETURNED",
+	"PENDING_RETAILER_CONFIRMATION",
+	"DAMAGED_OR_MISSING",
+}
+
+// ParseOrderStateCheck converts "off", "warn", or "block" to an OrderStateCheck.
+func ParseOrderStateCheck(value string) (OrderStateCheck, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "off":
+		return OrderStateCheckOff, nil
+	case string(OrderStateCheckWarn):
+		return OrderStateCheckWarn, nil
+	case string(OrderStateCheckBlock):
+		return OrderStateCheckBlock, nil
+	}
+	return OrderStateCheckOff, fmt.Errorf("unknown order state check %q (use off, warn, or block)", value)
+}
+
+// orderShippingProblem returns why shipments should not be added to order, or an empty string when it can be shipped.
+func orderShippingProblem(order Order) string {
+	if containsState(unshippableOrderStates, order.State) {
+		return fmt.Sprintf("order is %s", strings.ToUpper(order.State))
+	}
+	if order.HasPendingRetailerCancellationRequest {
+		return "retailer has requested cancellation of the order"
+	}
+	return ""
+}
@@ -1,13 +1,16 @@
 package app
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 const (
@@ -21,8 +24,8 @@ const ordersPageSize = 50
 
 // OrderClient retrieves orders needed to build an order-export CSV.
 type OrderClient interface {
-	GetAllOrders(apiToken string, limit int, page int, excludedStates string) ([]byte, error)
-	GetOrderByID(orderIdentifier string, apiToken string) ([]byte, error)
+	GetAllOrders(ctx context.Context, apiToken string, limit int, page int, excludedStates string) ([]byte, error)
+	GetOrderByID(ctx context.Context, orderIdentifier string, apiToken string) ([]byte, error)
 }
 
 // OrderExportFilter chooses either one Faire state or an explicit set of order identifiers.
@@ -45,34 +48,53 @@ var faireOrderStates = []string{
 	"DAMAGED_OR_MISSING",
 }
 
+// OrderProgress reports how far an order retrieval has progressed.
+type OrderProgress struct {
+	// Page is the page of results just retrieved when listing orders by state; it is 0 for lookups by identifier.
+	Page int
+	// Retrieved counts the orders kept so far.
+	Retrieved int
+	// Done and Total count lookups by identifier. Total is 0 when listing by state, because Faire does not report
+	// the number of pages in advance.
+	Done  int
+	Total int
+	// OrderIdentifier is the identifier just looked up; it is empty when listing by state and in the initial report.
+	OrderIdentifier string
+}
+
+// activeOrderStates lists the states shown when listing a sale source's open orders.
+var activeOrderStates = []string{OrderStateNew, "PROCESSING"}
+
 // ExportNewOrdersToCSV exports all NEW orders for saleSource to filename and returns the order count.
 // Relative filenames are created in the user's Downloads folder; absolute filenames are honored.
-func (c *FaireClient) ExportNewOrdersToCSV(saleSource, filename string) (int, error) {
-	return c.exportOrdersForState(saleSource, filename, OrderStateNew)
+func (c *FaireClient) ExportNewOrdersToCSV(ctx context.Context, saleSource, filename string) (int, error) {
+	return c.exportOrdersForState(ctx, saleSource, filename, OrderStateNew)
 }
 
 // ExportBackorderedOrdersToCSV exports all BACKORDERED orders for saleSource to filename and returns the order count.
 // Relative filenames are created in the user's Downloads folder; absolute filenames are honored.
-func (c *FaireClient) ExportBackorderedOrdersToCSV(saleSource, filename string) (int, error) {
-	return c.exportOrdersForState(saleSource, filename, OrderStateBackordered)
+func (c *FaireClient) ExportBackorderedOrdersToCSV(ctx context.Context, saleSource, filename string) (int, error) {
+	return c.exportOrdersForState(ctx, saleSource, filename, OrderStateBackordered)
 }
 
 // ExportOrdersByIDsToCSV exports the supplied display IDs or Faire bo_ IDs for saleSource to filename.
 // Relative filenames are created in the user's Downloads folder; absolute filenames are honored.
-func (c *FaireClient) ExportOrdersByIDsToCSV(saleSource, filename string, orderIdentifiers []string) (int, error) {
+func (c *FaireClient) ExportOrdersByIDsToCSV(ctx context.Context, saleSource, filename string, orderIdentifiers []string) (int, error) {
 	token, err := tokenForSaleSource(saleSource)
 	if err != nil {
 		return 0, err
 	}
 
-	return ExportOrdersToCSV(c, token, saleSource, filename, OrderExportFilter{
+	return ExportOrdersToCSV(ctx, c, token, saleSource, filename, OrderExportFilter{
 		OrderIdentifiers: orderIdentifiers,
-	})
+	}, nil)
 }
 
 // ExportOrdersToCSV retrieves the orders selected by filter and writes them to filename.
 // apiToken authenticates requests, saleSource is recorded in the CSV, and relative filenames are created in Downloads.
-func ExportOrdersToCSV(client OrderClient, apiToken, saleSource, filename string, filter OrderExportFilter) (int, error) {
+// Canceling ctx stops retrieving orders, and no file is written. onProgress, when not nil, is called once before the
+// first request and again after each page or order is retrieved.
+func ExportOrdersToCSV(ctx context.Context, client OrderClient, apiToken, saleSource, filename string, filter OrderExportFilter, onProgress func(OrderProgress)) (int, error) {
 	if err := filter.validate(); err != nil {
 		return 0, err
 	}
@@ -82,9 +104,12 @@ func ExportOrdersToCSV(client OrderClient, apiToken, saleSource, filename string
 		err    error
 	)
 	if len(filter.OrderIdentifiers) > 0 {
-		orders, err = getOrdersByIdentifiers(client, apiToken, filter.OrderIdentifiers)
+		orders, err = getOrdersByIdentifiers(ctx, client, apiToken, filter.OrderIdentifiers, onProgress)
 	} else {
-		orders, err = getOrdersByState(client, apiToken, filter.State)
+		orders, err = getOrdersByState(ctx, client, apiToken, filter.State, onProgress)
+	}
+	if errors.Is(err, context.Canceled) {
+		return 0, fmt.Errorf("export canceled after %d orders were retrieved; no file was written: %w", len(orders), err)
 	}
 	if err != nil {
 		return 0, err
@@ -96,13 +121,13 @@ func ExportOrdersToCSV(client OrderClient, apiToken, saleSource, filename string
 	return len(orders), nil
 }
 
-// ParseOrderIdentifiers returns unique non-empty identifiers entered as comma-, semicolon-, or line-separated values.
+// ParseOrderIdentifiers returns unique non-empty identifiers entered as comma-, semicolon-, space-, or line-separated values.
 func ParseOrderIdentifiers(raw string) []string {
 	seen := make(map[string]struct{})
 	identifiers := make([]string, 0)
 
 	for _, identifier := range strings.FieldsFunc(raw, func(r rune) bool {
-		return r == ',' || r == ';' || r == '\n' || r == '\r'
+		return r == ',' || r == ';' || unicode.IsSpace(r)
 	}) {
 		identifier = strings.TrimSpace(identifier)
 		if identifier == "" {
@@ -133,12 +158,12 @@ func OrderIdentifierToOrderID(orderIdentifier string) string {
 }
 
 // exportOrdersForState resolves the sale-source token and exports the requested Faire state.
-func (c *FaireClient) exportOrdersForState(saleSource, filename, state string) (int, error) {
+func (c *FaireClient) exportOrdersForState(ctx context.Context, saleSource, filename, state string) (int, error) {
 	token, err := tokenForSaleSource(saleSource)
 	if err != nil {
 		return 0, err
 	}
-	return ExportOrdersToCSV(c, token, saleSource, filename, OrderExportFilter{State: state})
+	return ExportOrdersToCSV(ctx, c, token, saleSource, filename, OrderExportFilter{State: state}, nil)
 }
 
 // tokenForSaleSource returns the configured token or a consistent error for an invalid or unconfigured sale source.
@@ -164,27 +189,42 @@ func (filter OrderExportFilter) validate() error {
 	return nil
 }
 
+// GetActiveOrders returns every NEW or PROCESSING order visible to apiToken.
+// onProgress, when not nil, is called once before the first request and again after each page is retrieved.
+func GetActiveOrders(ctx context.Context, client OrderClient, apiToken string, onProgress func(OrderProgress)) ([]Order, error) {
+	return getOrdersInStates(ctx, client, apiToken, activeOrderStates, onProgress)
+}
+
 // getOrdersByState paginates Faire's inverse state filter and retains only the requested state as a safeguard.
-func getOrdersByState(client OrderClient, apiToken, state string) ([]Order, error) {
-	excludedStates := excludedStatesFor(state)
+func getOrdersByState(ctx context.Context, client OrderClient, apiToken, state string, onProgress func(OrderProgress)) ([]Order, error) {
+	return getOrdersInStates(ctx, client, apiToken, []string{state}, onProgress)
+}
+
+// getOrdersInStates paginates Faire's inverse state filter and retains only orders in states as a safeguard.
+// On failure it returns the orders retrieved from earlier pages along with the error.
+func getOrdersInStates(ctx context.Context, client OrderClient, apiToken string, states []string, onProgress func(OrderProgress)) ([]Order, error) {
+	excludedStates := excludedStatesFor(states...)
+	description := strings.Join(states, "/")
 	orders := make([]Order, 0)
+	reportOrderProgress(onProgress, OrderProgress{})
 
 	for page := 1; ; page++ {
-		response, err := client.GetAllOrders(apiToken, ordersPageSize, page, excludedStates)
+		response, err := client.GetAllOrders(ctx, apiToken, ordersPageSize, page, excludedStates)
 		if err != nil {
-			return nil, fmt.Errorf("get %s orders on page %d: %w", state, page, err)
+			return orders, fmt.Errorf("get %s orders on page %d: %w", description, page, err)
 		}
 
 		var ordersResponse Orders
 		if err := json.Unmarshal(response, &ordersResponse); err != nil {
-			return nil, fmt.Errorf("parse %s orders on page %d: %w", state, page, err)
+			return orders, fmt.Errorf("parse %s orders on page %d: %w", description, page, err)
 		}
 		for _, order := range ordersResponse.Orders {
 			// Keep the local check because the export must never include a wrong state if Faire ignores a filter.
-			if strings.EqualFold(order.State, state) {
+			if containsState(states, order.State) {
 				orders = append(orders, order)
 			}
 		}
+		reportOrderProgress(onProgress, OrderProgress{Page: page, Retrieved: len(orders)})
 
 		if len(ordersResponse.Orders) < ordersPageSize {
 			return orders, nil
@@ -193,34 +233,59 @@ func getOrdersByState(client OrderClient, apiToken, state string) ([]Order, erro
 }
 
 // getOrdersByIdentifiers retrieves each requested order in user-entered order.
-func getOrdersByIdentifiers(client OrderClient, apiToken string, orderIdentifiers []string) ([]Order, error) {
+// On failure it returns the orders retrieved before the failing one along with the error.
+func getOrdersByIdentifiers(ctx context.Context, client OrderClient, apiToken string, orderIdentifiers []string, onProgress func(OrderProgress)) ([]Order, error) {
 	orders := make([]Order, 0, len(orderIdentifiers))
+	reportOrderProgress(onProgress, OrderProgress{Total: len(orderIdentifiers)})
 	for _, orderIdentifier := range orderIdentifiers {
-		response, err := client.GetOrderByID(orderIdentifier, apiToken)
+		response, err := client.GetOrderByID(ctx, orderIdentifier, apiToken)
 		if err != nil {
-			return nil, fmt.Errorf("get order %q: %w", orderIdentifier, err)
+			return orders, fmt.Errorf("get order %q: %w", orderIdentifier, err)
 		}
 
 		var order Order
 		if err := json.Unmarshal(response, &order); err != nil {
-			return nil, fmt.Errorf("parse order %q: %w", orderIdentifier, err)
+			return orders, fmt.Errorf("parse order %q: %w", orderIdentifier, err)
 		}
 		orders = append(orders, order)
+		reportOrderProgress(onProgress, OrderProgress{
+			Retrieved:       len(orders),
+			Done:            len(orders),
+			Total:           len(orderIdentifiers),
+			OrderIdentifier: orderIdentifier,
+		})
 	}
 	return orders, nil
 }
 
-// excludedStatesFor returns all Faire order states except state for Faire's inverse state filter.
-func excludedStatesFor(state string) string {
-	excludedStates := make([]string, 0, len(faireOrderStates)-1)
+// reportOrderProgress calls onProgress with progress unless onProgress is nil.
+func reportOrderProgress(onProgress func(OrderProgress), progress OrderProgress) {
+	if onProgress != nil {
+		onProgress(progress)
+	}
+}
+
+// excludedStatesFor returns all Faire order states except states for Faire's inverse state filter.
+func excludedStatesFor(states ...string) string {
+	excludedStates := make([]string, 0, len(faireOrderStates))
 	for _, candidate := range faireOrderStates {
-		if candidate != state {
+		if !containsState(states, candidate) {
 			excludedStates = append(excludedStates, candidate)
 		}
 	}
 	return strings.Join(excludedStates, ",")
 }
 
+// containsState reports whether state case-insensitively matches one of states.
+func containsState(states []string, state string) bool {
+	for _, candidate := range states {
+		if strings.EqualFold(candidate, state) {
+			return true
+		}
+	}
+	return false
+}
+
 // isKnownOrderState reports whether state can be represented by the inverse state filter.
 func isKnownOrderState(state string) bool {
 	for _, candidate := range faireOrderStates {
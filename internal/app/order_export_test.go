@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
@@ -22,13 +23,13 @@ type exportTestClient struct {
 }
 
 // GetAllOrders records the inverse filter and returns the configured page of test orders.
-func (c *exportTestClient) GetAllOrders(apiToken string, limit int, page int, excludedStates string) ([]byte, error) {
+func (c *exportTestClient) GetAllOrders(ctx context.Context, apiToken string, limit int, page int, excludedStates string) ([]byte, error) {
 	c.excludedStates = append(c.excludedStates, excludedStates)
 	return json.Marshal(Orders{Page: page, Limit: limit, Orders: c.ordersByPage[page]})
 }
 
 // GetOrderByID records and returns the configured order for orderIdentifier.
-func (c *exportTestClient) GetOrderByID(orderIdentifier string, apiToken string) ([]byte, error) {
+func (c *exportTestClient) GetOrderByID(ctx context.Context, orderIdentifier string, apiToken string) ([]byte, error) {
 	c.requestedIDs = append(c.requestedIDs, orderIdentifier)
 	return json.Marshal(c.ordersByID[orderIdentifier])
 }
@@ -40,7 +41,7 @@ func TestExportOrdersToCSVByState(t *testing.T) {
 	}}
 	filename := filepath.Join(t.TempDir(), "backordered.csv")
 
-	count, err := ExportOrdersToCSV(client, "token", "bsc", filename, OrderExportFilter{State: OrderStateBackordered})
+	count, err := ExportOrdersToCSV(context.Background(), client, "token", "bsc", filename, OrderExportFilter{State: OrderStateBackordered}, nil)
 	if err != nil {
 		t.Fatalf("ExportOrdersToCSV returned an error: %v", err)
 	}
@@ -74,7 +75,7 @@ func TestExportOrdersToCSVByState(t *testing.T) {
 
 // TestExportOrdersToCSVByIdentifiers confirms a user-supplied order list preserves order and deduplicates identifiers.
 func TestExportOrdersToCSVByIdentifiers(t *testing.T) {
-	identifiers := ParseOrderIdentifiers("FIRST-1, second-2\nFIRST-1")
+	identifiers := ParseOrderIdentifiers("FIRST-1, second-2\nFIRST-1 first-1")
 	wantIdentifiers := []string{"FIRST-1", "second-2"}
 	if !reflect.DeepEqual(identifiers, wantIdentifiers) {
 		t.Fatalf("ParseOrderIdentifiers() = %#v, want %#v", identifiers, wantIdentifiers)
@@ -86,7 +87,7 @@ func TestExportOrdersToCSVByIdentifiers(t *testing.T) {
 	}}
 	filename := filepath.Join(t.TempDir(), "selected.csv")
 
-	count, err := ExportOrdersToCSV(client, "token", "asc", filename, OrderExportFilter{OrderIdentifiers: identifiers})
+	count, err := ExportOrdersToCSV(context.Background(), client, "token", "asc", filename, OrderExportFilter{OrderIdentifiers: identifiers}, nil)
 	if err != nil {
 		t.Fatalf("ExportOrdersToCSV returned an error: %v", err)
 	}
@@ -106,6 +107,51 @@ func TestExportOrdersToCSVByIdentifiers(t *testing.T) {
 	}
 }
 
+// TestExportOrdersToCSVReportsProgress confirms each lookup by identifier is reported with a running count.
+func TestExportOrdersToCSVReportsProgress(t *testing.T) {
+	client := &exportTestClient{ordersByID: map[string]Order{
+		"FIRST-1":  testOrder("bo_first", "FIRST-1", OrderStateNew),
+		"SECOND-2": testOrder("bo_second", "SECOND-2", OrderStateNew),
+	}}
+	var reports []OrderProgress
+	filter := OrderExportFilter{OrderIdentifiers: []string{"FIRST-1", "SECOND-2"}}
+	if _, err := ExportOrdersToCSV(context.Background(), client, "token", "bsc", filepath.Join(t.TempDir(), "selected.csv"), filter, func(p OrderProgress) {
+		reports = append(reports, p)
+	}); err != nil {
+		t.Fatalf("ExportOrdersToCSV returned an error: %v", err)
+	}
+
+	want := []OrderProgress{
+		{Total: 2},
+		{Retrieved: 1, Done: 1, Total: 2, OrderIdentifier: "FIRST-1"},
+		{Retrieved: 2, Done: 2, Total: 2, OrderIdentifier: "SECOND-2"},
+	}
+	if !reflect.DeepEqual(reports, want) {
+		t.Errorf("progress reports = %+v, want %+v", reports, want)
+	}
+}
+
+// TestGetActiveOrders confirms the active-order listing excludes every state except NEW and PROCESSING.
+func TestGetActiveOrders(t *testing.T) {
+	client := &exportTestClient{ordersByPage: map[int][]Order{
+		1: {testOrder("bo_new", "NEW-1", OrderStateNew), testOrder("bo_processing", "PROC-1", "PROCESSING"), testOrder("bo_delivered", "DEL-1", "DELIVERED")},
+	}}
+
+	orders, err := GetActiveOrders(context.Background(), client, "token", nil)
+	if err != nil {
+		t.Fatalf("GetActiveOrders returned an error: %v", err)
+	}
+	if len(orders) != 2 || orders[0].ID != "bo_new" || orders[1].ID != "bo_processing" {
+		t.Fatalf("GetActiveOrders() = %+v, want bo_new and bo_processing", orders)
+	}
+	if strings.Contains(client.excludedStates[0], OrderStateNew) || strings.Contains(client.excludedStates[0], "PROCESSING") {
+		t.Errorf("inverse filter %q excludes an active state", client.excludedStates[0])
+	}
+	if !strings.Contains(client.excludedStates[0], "DELIVERED") {
+		t.Errorf("inverse filter %q does not exclude DELIVERED", client.excludedStates[0])
+	}
+}
+
 // TestDownloadsFilePath returns a created Downloads path under the current user's home directory.
 func TestDownloadsFilePath(t *testing.T) {
 	homeDirectory := t.TempDir()
@@ -125,7 +171,7 @@ func TestDownloadsFilePath(t *testing.T) {
 
 // TestExportOrdersToCSVRejectsAnEmptyFilter prevents an accidental unfiltered order export.
 func TestExportOrdersToCSVRejectsAnEmptyFilter(t *testing.T) {
-	_, err := ExportOrdersToCSV(&exportTestClient{}, "token", "bsc", filepath.Join(t.TempDir(), "orders.csv"), OrderExportFilter{})
+	_, err := ExportOrdersToCSV(context.Background(), &exportTestClient{}, "token", "bsc", filepath.Join(t.TempDir(), "orders.csv"), OrderExportFilter{}, nil)
 	if err == nil || !strings.Contains(err.Error(), "unsupported order state") {
 		t.Fatalf("ExportOrdersToCSV empty filter error = %v, want unsupported state error", err)
 	}
@@ -173,13 +219,13 @@ func TestFaireClientOrderRequests(t *testing.T) {
 	})}
 
 	client := &FaireClient{BaseURL: "https://faire.test"}
-	if _, err := client.GetAllOrders("test-token", 50, 3, "NEW,BACKORDERED"); err != nil {
+	if _, err := client.GetAllOrders(context.Background(), "test-token", 50, 3, "NEW,BACKORDERED"); err != nil {
 		t.Fatalf("GetAllOrders returned an error: %v", err)
 	}
-	if _, err := client.GetOrderByID("BO_ABC123", "test-token"); err != nil {
+	if _, err := client.GetOrderByID(context.Background(), "BO_ABC123", "test-token"); err != nil {
 		t.Fatalf("GetOrderByID with raw Faire ID returned an error: %v", err)
 	}
-	if _, err := client.GetOrderByID("missing", "test-token"); err == nil || !strings.Contains(err.Error(), "404") {
+	if _, err := client.GetOrderByID(context.Background(), "missing", "test-token"); err == nil || !strings.Contains(err.Error(), "404") {
 		t.Fatalf("GetOrderByID non-success error = %v, want HTTP 404 error", err)
 	}
 }
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ShipmentPreviewOptions controls the checks made by PreviewShipments.
+type ShipmentPreviewOptions struct {
+	// VerifyOrders looks up every order with Faire and skips shipments for missing orders and tracking codes that
+	// are already on the order.
+	VerifyOrders bool
+	// OrderStateCheck, with VerifyOrders, skips or warns about shipments for orders in a state that should not be
+	// shipped or with a pending cancellation request, as ShipmentProcessOptions.OrderStateCheck would on submit.
+	OrderStateCheck OrderStateCheck
+	// Profile selects the export's columns and Faire rows; nil uses DefaultShipmentCSVProfile.
+	Profile *ShipmentCSVProfile
+}
+
+// ShipmentPreview describes what ProcessShipmentsWithOptions would send for a CSV without posting anything.
+type ShipmentPreview struct {
+	// Payloads are the shipments that would be posted, in CSV row order.
+	Payloads []ShipmentPayload `json:"payloads"`
+	// SkippedRows are source rows that would never be submitted, such as rows with an unknown sale source.
+	SkippedRows []SkippedRow `json:"skipped_rows"`
+	// Warnings flags shipments with suspicious data, such as a tracking code with a bad check digit.
+	Warnings []ShipmentWarning `json:"warnings"`
+	// Skipped are the shipments that order verification would hold back and why.
+	Skipped []SkippedShipment `json:"skipped"`
+	// Requests is the number of shipment requests that would be sent.
+	Requests int `json:"requests"`
+	// OrdersVerified reports whether the orders were checked with Faire.
+	OrdersVerified bool `json:"orders_verified"`
+}
+
+// SkippedShipment is a shipment that will not be posted, with a user-facing reason.
+type SkippedShipment struct {
+	Payload ShipmentPayload `json:"payload"`
+	Reason  string          `json:"reason"`
+}
+
+// PreviewShipments parses the CSV or XLSX export at csvPath and reports the shipments that would be posted and skipped.
+// client is used only when options.VerifyOrders is set, and err reports CSV parsing failures.
+func PreviewShipments(ctx context.Context, csvPath string, client FaireClientInterface, options ShipmentPreviewOptions) (ShipmentPreview, error) {
+	preview := ShipmentPreview{OrdersVerified: options.VerifyOrders}
+	profile := profileOrDefault(options.Profile)
+	parsed, err := ReadShipmentsFile(csvPath, profile)
+	if err != nil {
+		return preview, err
+	}
+	plan := planShipments(parsed.Shipments, profile.Columns)
+	preview.SkippedRows = plan.skippedRows(parsed.Skipped)
+	preview.Warnings = parsed.Warnings
+
+	skipReasons := make(map[*shipmentBatch][]string)
+	orderWarnings := make(map[*shipmentBatch]string)
+	if options.VerifyOrders {
+		for _, batch := range plan.batches {
+			if err := ctx.Err(); err != nil {
+				return preview, fmt.Errorf("verify orders: %w", err)
+			}
+			skipReasons[batch], orderWarnings[batch] = batch.verify(ctx, client, options.OrderStateCheck)
+		}
+	}
+
+	requests := make(map[*shipmentBatch]struct{})
+	for _, ref := range plan.refs {
+		payload := ref.batch.payloads[ref.index]
+		if reasons := skipReasons[ref.batch]; reasons != nil && reasons[ref.index] != "" {
+			preview.Skipped = append(preview.Skipped, SkippedShipment{Payload: payload, Reason: reasons[ref.index]})
+			continue
+		}
+		if warning := orderWarnings[ref.batch]; warning != "" {
+			preview.Warnings = append(preview.Warnings, ShipmentWarning{
+				Line:         ref.line,
+				TrackingCode: payload.TrackingCode,
+				Message:      fmt.Sprintf("%s (order %s)", warning, OrderIDToDisplayID(payload.OrderID)),
+			})
+		}
+		preview.Payloads = append(preview.Payloads, payload)
+		requests[ref.batch] = struct{}{}
+	}
+	preview.Requests = len(requests)
+	return preview, nil
+}
+
+// verify looks up the batch's order and returns a skip reason for each payload, or an empty reason for payloads that would be posted,
+// and the problem with the order when check only warns about it.
+func (batch *shipmentBatch) verify(ctx context.Context, client FaireClientInterface, check OrderStateCheck) ([]string, string) {
+	reasons := make([]string, len(batch.payloads))
+	skipAll := func(reason string) []string {
+		for i := range reasons {
+			reasons[i] = reason
+		}
+		return reasons
+	}
+
+	order, err := fetchOrder(ctx, client, batch.orderID, batch.apiToken)
+	if err != nil {
+		return skipAll(fmt.Sprintf("order lookup failed: %v", err)), ""
+	}
+	var warning string
+	if problem := orderShippingProblem(order); problem != "" {
+		switch check {
+		case OrderStateCheckBlock:
+			return skipAll(problem), ""
+		case OrderStateCheckWarn:
+			warning = problem
+		}
+	}
+
+	existing := orderTrackingCodes(order)
+	duplicates := batch.findDuplicates(existing)
+	for i, payload := range batch.payloads {
+		if earlier, duplicate := duplicates[i]; duplicate {
+			reasons[i] = "duplicate of " + batch.describe(earlier)
+			continue
+		}
+		trackingCode := normalizeTrackingCode(payload.TrackingCode)
+		if _, present := existing[trackingCode]; present && trackingCode != "" {
+			reasons[i] = "tracking code is already on the order"
+		}
+	}
+	return reasons, warning
+}
+
+// FormatShipmentPreview returns a readable summary of preview for confirmation dialogs and terminal output.
+func FormatShipmentPreview(preview ShipmentPreview) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s would be posted in %s; %d would be skipped.\n",
+		countNoun(len(preview.Payloads), "shipment", "shipments"), countNoun(preview.Requests, "request", "requests"),
+		len(preview.Skipped)+CountUnexpectedSkips(preview.SkippedRows))
+	if !preview.OrdersVerified {
+		b.WriteString("Orders have not been checked with Faire.\n")
+	}
+
+	b.WriteString("\nWould post:\n")
+	if len(preview.Payloads) == 0 {
+		b.WriteString("  None\n")
+	}
+	for _, payload := range preview.Payloads {
+		fmt.Fprintf(&b, "  %s  %s %s  $%.2f  (%s, %s)\n",
+			OrderIDToDisplayID(payload.OrderID), payload.Carrier, payload.TrackingCode,
+			float64(payload.MakerCostCents)/100, payload.SaleSource, payload.ShippingType)
+	}
+
+	b.WriteString("\nWould skip:\n")
+	if len(preview.Skipped) == 0 {
+		b.WriteString("  None\n")
+	}
+	for _, skipped := range preview.Skipped {
+		fmt.Fprintf(&b, "  %s  %s %s: %s\n",
+			OrderIDToDisplayID(skipped.Payload.OrderID), skipped.Payload.Carrier, skipped.Payload.TrackingCode, skipped.Reason)
+	}
+
+	b.WriteString("\nSkipped rows:\n")
+	b.WriteString(FormatSkippedRows(preview.SkippedRows))
+
+	if len(preview.Warnings) > 0 {
+		b.WriteString("\nWarnings:\n")
+		b.WriteString(FormatShipmentWarnings(preview.Warnings))
+	}
+	return b.String()
+}
+
+// countNoun returns n followed by singular when n is one and by plural otherwise, as in "1 shipment" or "2 shipments".
+func countNoun(n int, singular, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPreviewShipments(t *testing.T) {
+	t.Setenv("BSC_API_TOKEN", "dummy-token")
+	t.Setenv("SMD_API_TOKEN", "")
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,OPEN,TRACK1,1.00,UPS,Prepaid,0090671,BSC
+DOC2,OPEN,TRACK2,2.00,UPS,Prepaid,0090671,BSC
+DOC3,CANCELLED,TRACK3,3.00,UPS,Prepaid,0090671,BSC
+DOC4,NOTOKEN,TRACK4,4.00,UPS,Prepaid,0090671,SM
+DOC5,MISSING,TRACK5,5.00,UPS,Prepaid,0090671,BSC
+`
+	path := writeShipmentsCSV(t, csvContent)
+
+	mockClient := &MockFaireClient{Orders: []Order{
+		{ID: "bo_open", DisplayID: "OPEN", State: OrderStateNew, Shipments: []OrderShipment{{TrackingCode: "TRACK2"}}},
+		{ID: "bo_cancelled", DisplayID: "CANCELLED", State: "CANCELED"},
+	}}
+
+	local, err := PreviewShipments(context.Background(), path, mockClient, ShipmentPreviewOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mockClient.CallCount != 0 {
+		t.Errorf("unverified preview made %d API calls, want 0", mockClient.CallCount)
+	}
+	if len(local.Payloads) != 4 || local.Requests != 3 {
+		t.Errorf("unverified preview = %d payloads in %d requests, want 4 in 3", len(local.Payloads), local.Requests)
+	}
+	if len(local.SkippedRows) != 1 || local.SkippedRows[0].Kind != SkipMissingToken || local.SkippedRows[0].Line != 5 {
+		t.Errorf("unverified preview skipped rows = %+v, want line 5 without a token", local.SkippedRows)
+	}
+
+	verified, err := PreviewShipments(context.Background(), path, mockClient, ShipmentPreviewOptions{VerifyOrders: true, OrderStateCheck: OrderStateCheckBlock})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(verified.Payloads) != 1 || verified.Payloads[0].TrackingCode != "TRACK1" || verified.Requests != 1 {
+		t.Errorf("verified preview payloads = %+v in %d requests, want only TRACK1 in 1", verified.Payloads, verified.Requests)
+	}
+	wantReasons := map[string]string{
+		"TRACK2": "already on the order",
+		"TRACK3": "order is CANCELED",
+		"TRACK5": "order lookup failed",
+	}
+	if len(verified.Skipped) != len(wantReasons) {
+		t.Fatalf("verified preview skipped = %+v, want %d rows", verified.Skipped, len(wantReasons))
+	}
+	for _, skipped := range verified.Skipped {
+		if want := wantReasons[skipped.Payload.TrackingCode]; !strings.Contains(skipped.Reason, want) {
+			t.Errorf("skip reason for %s = %q, want it to contain %q", skipped.Payload.TrackingCode, skipped.Reason, want)
+		}
+	}
+	if text := FormatShipmentPreview(verified); !strings.Contains(text, "1 shipment would be posted in 1 request; 4 would be skipped.") {
+		t.Errorf("FormatShipmentPreview() = %q, want a summary line", text)
+	}
+	if text := FormatShipmentPreview(local); !strings.Contains(text, "4 shipments would be posted in 3 requests; 1 would be skipped.") {
+		t.Errorf("FormatShipmentPreview() = %q, want a plural summary line", text)
+	}
+
+	// With the check set to warn, the canceled order's shipment would be posted and reported as a warning, as on submit.
+	warned, err := PreviewShipments(context.Background(), path, mockClient, ShipmentPreviewOptions{VerifyOrders: true, OrderStateCheck: OrderStateCheckWarn})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(warned.Payloads) != 2 || warned.Payloads[1].TrackingCode != "TRACK3" {
+		t.Errorf("warned preview payloads = %+v, want TRACK1 and TRACK3", warned.Payloads)
+	}
+	if len(warned.Warnings) != len(local.Warnings)+1 {
+		t.Fatalf("warned preview warnings = %+v, want one more than the unverified preview", warned.Warnings)
+	}
+	if last := warned.Warnings[len(warned.Warnings)-1]; last.Line != 4 || !strings.Contains(last.Message, "order is CANCELED") {
+		t.Errorf("order warning = %+v, want the canceled order on line 4", last)
+	}
+}
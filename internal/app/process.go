@@ -1,6 +1,14 @@
 package app
 
-import "strings"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
 
 // DisplayIDToOrderID converts a display ID (e.g., "BXDMJBWXID") to an order ID (e.g., "bo_bxdmjbwxid").
 func DisplayIDToOrderID(displayID string) string {
@@ -15,38 +23,531 @@ func OrderIDToDisplayID(orderID string) string {
 	return strings.ToUpper(strings.TrimPrefix(orderID, "bo_"))
 }
 
-// ProcessShipments submits shipments from csvPath and returns the successful and failed payloads.
+// ShipmentProcessOptions controls how ProcessShipmentsWithOptions submits shipment requests.
+type ShipmentProcessOptions struct {
+	// Workers is the number of concurrent requests per sale source.
+	// Values below 1 submit every request sequentially in CSV order, as does a MockFaireClient with FailOnCall set.
+	Workers int
+	// SaleSourceWorkers overrides Workers for individual sale sources, keyed case-insensitively.
+	// It applies only when Workers is at least 1.
+	SaleSourceWorkers map[string]int
+	// SkipExistingShipments fetches each order before posting and skips shipments whose tracking code is already on it,
+	// which makes re-running a CSV after a partial failure safe.
+	SkipExistingShipments bool
+	// OrderStateCheck fetches each order before posting and warns about or blocks shipments for orders in a state
+	// that should not be shipped, or with a pending retailer cancellation request.
+	OrderStateCheck OrderStateCheck
+	// Profile selects the export's columns and Faire rows; nil uses DefaultShipmentCSVProfile.
+	Profile *ShipmentCSVProfile
+	// OnProgress, when set, is called once before submission and again as each shipment completes.
+	// Calls are serialized, but they may come from worker goroutines.
+	OnProgress func(ShipmentProgress)
+}
+
+// ShipmentProgress reports how many shipments have completed during ProcessShipmentsWithOptions.
+type ShipmentProgress struct {
+	Done           int
+	Total          int
+	Succeeded      int
+	Failed         int
+	AlreadyPresent int
+	Duplicates     int
+	// Payload is the shipment that just completed, and Outcome how it completed; both are empty in the initial report.
+	Payload ShipmentPayload
+	Outcome RunOutcome
+}
+
+// ShipmentResults groups the payloads from one ProcessShipmentsWithOptions run by outcome, each in CSV row order.
+type ShipmentResults struct {
+	Processed []ShipmentPayload `json:"processed"`
+	Failed    []ShipmentPayload `json:"failed"`
+	// AlreadyPresent holds shipments whose tracking code was already on the Faire order, so they were not posted again.
+	AlreadyPresent []ShipmentPayload `json:"already_present"`
+	// Duplicates holds shipments whose tracking code repeats an earlier row for the same order, so they were not
+	// posted a second time. ErrorMsg names the earlier row.
+	Duplicates []ShipmentPayload `json:"duplicates"`
+	// SkippedRows holds source rows that were never submitted, such as rows with an unknown sale source.
+	SkippedRows []SkippedRow `json:"skipped_rows"`
+	// Warnings flags submitted shipments with suspicious data, such as a tracking code with a bad check digit.
+	Warnings []ShipmentWarning `json:"warnings"`
+	// Canceled reports that the run was canceled before every shipment was sent.
+	// Shipments that were never sent are in Failed with FailureNotSent, so they can be saved and resubmitted.
+	// Shipments whose request was abandoned in flight are in Failed with FailureOutcomeUnknown: Faire may have
+	// created them, so check those orders, or resubmit with existing shipments skipped.
+	Canceled bool `json:"canceled"`
+
+	// columns names the export's columns, so skipped rows can be reported by field; zero means the default profile's.
+	columns ShipmentColumns
+}
+
+// ProcessShipments submits shipments from csvPath sequentially and returns the successful and failed payloads.
 // client performs Faire API requests, and err reports CSV parsing failures.
-func ProcessShipments(csvPath string, client FaireClientInterface) (processed []ShipmentPayload, failed []ShipmentPayload, err error) {
-	shipments, parseErr := ParseShipmentsCSV(csvPath)
-	if parseErr != nil {
-		err = parseErr
-		return
+func ProcessShipments(ctx context.Context, csvPath string, client FaireClientInterface) (processed []ShipmentPayload, failed []ShipmentPayload, err error) {
+	results, err := ProcessShipmentsWithOptions(ctx, csvPath, client, ShipmentProcessOptions{})
+	return results.Processed, results.Failed, err
+}
+
+// ProcessShipmentsWithOptions submits shipments from the CSV or XLSX export at csvPath as configured by options.
+// Shipments for the same order and sale source are sent in one request, and err reports CSV parsing failures.
+// Canceling ctx stops sending further requests and abandons those in flight; the results then report what completed
+// and set Canceled.
+func ProcessShipmentsWithOptions(ctx context.Context, csvPath string, client FaireClientInterface, options ShipmentProcessOptions) (ShipmentResults, error) {
+	profile := profileOrDefault(options.Profile)
+	results := ShipmentResults{columns: profile.Columns}
+	parsed, err := ReadShipmentsFile(csvPath, profile)
+	if err != nil {
+		return results, err
 	}
-	shippingType := "SHIP_ON_YOUR_OWN"
+	plan := planShipments(parsed.Shipments, profile.Columns)
+	results.SkippedRows = plan.skippedRows(parsed.Skipped)
+	results.Warnings = parsed.Warnings
+
+	submitShipmentBatches(ctx, client, plan.batches, options)
+	plan.collect(&results)
+	return results, nil
+}
+
+// collect appends every planned payload to results by outcome, in plan order.
+func (plan shipmentPlan) collect(results *ShipmentResults) {
+	for _, ref := range plan.refs {
+		payload := ref.batch.payloads[ref.index]
+		outcome := ref.batch.outcomes[ref.index]
+		if outcome.warning != "" {
+			results.Warnings = append(results.Warnings, ShipmentWarning{
+				Line:         ref.line,
+				TrackingCode: payload.TrackingCode,
+				Message:      fmt.Sprintf("%s (order %s)", outcome.warning, OrderIDToDisplayID(payload.OrderID)),
+			})
+		}
+		switch {
+		case outcome.err != nil:
+			// Preserve the API error in the result so the GUI can show the user which shipment failed.
+			payload.ErrorMsg = outcome.err.Error()
+			payload.FailureKind = classifyFailure(outcome.err)
+			results.Failed = append(results.Failed, payload)
+			if errors.Is(outcome.err, context.Canceled) {
+				results.Canceled = true
+			}
+		case outcome.alreadyPresent:
+			results.AlreadyPresent = append(results.AlreadyPresent, payload)
+		case outcome.duplicateOf != "":
+			payload.ErrorMsg = "duplicate of " + outcome.duplicateOf
+			results.Duplicates = append(results.Duplicates, payload)
+		default:
+			payload.Created = outcome.created
+			results.Processed = append(results.Processed, payload)
+		}
+	}
+}
+
+// shipmentPlan groups parsed shipments into the requests that would be sent to Faire.
+type shipmentPlan struct {
+	batches []*shipmentBatch
+	// refs lists every planned payload in CSV row order.
+	refs []shipmentRef
+	// skipped lists shipments that cannot be sent because their sale source has no token.
+	skipped []SkippedRow
+
+	batchByKey map[shipmentBatchKey]*shipmentBatch
+}
+
+// skippedRows returns the rows skipped while parsing and those skipped by the plan, sorted by source line.
+func (plan *shipmentPlan) skippedRows(parsed []SkippedRow) []SkippedRow {
+	rows := append(append([]SkippedRow(nil), parsed...), plan.skipped...)
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Line < rows[j].Line })
+	return rows
+}
+
+// planShipments builds one batch per order and sale-source token, preserving CSV row order in the plan's refs.
+// Shipments without a token are skipped, with their fields recorded under the export's columns.
+func planShipments(shipments []Shipment, columns ShipmentColumns) shipmentPlan {
+	plan := shipmentPlan{batchByKey: make(map[shipmentBatchKey]*shipmentBatch)}
 	for _, s := range shipments {
 		apiToken, tokenErr := GetToken(s.SaleSource)
 		if tokenErr != nil || apiToken == "" {
-			// Should not happen due to ParseShipmentsCSV, but skip just in case
+			plan.skipped = append(plan.skipped, SkippedRow{
+				Line:   s.Line,
+				Kind:   SkipMissingToken,
+				Reason: fmt.Sprintf("no API token is configured for sale source %q (PO %q)", s.SaleSource, s.PONumber),
+				Values: s.values(columns),
+			})
 			continue
 		}
-		orderID := DisplayIDToOrderID(s.PONumber)
-		payload := ShipmentPayload{
-			OrderID:        orderID,
+		shippingType := s.ShippingType
+		if shippingType == "" {
+			shippingType = ShippingTypeShipOnYourOwn
+		}
+		plan.add(ShipmentPayload{
+			OrderID:        DisplayIDToOrderID(s.PONumber),
 			MakerCostCents: s.MakerCostCents,
 			Carrier:        s.Carrier,
 			TrackingCode:   s.TrackingCode,
 			ShippingType:   shippingType,
 			SaleSource:     s.SaleSource,
+		}, apiToken, s.Line)
+	}
+	return plan
+}
+
+// add appends payload from the one-based source line to the batch for its order and apiToken, creating the batch on first use.
+// line is zero when the payload did not come from an export.
+func (plan *shipmentPlan) add(payload ShipmentPayload, apiToken string, line int) {
+	key := shipmentBatchKey{apiToken: apiToken, orderID: payload.OrderID}
+	batch, exists := plan.batchByKey[key]
+	if !exists {
+		batch = &shipmentBatch{apiToken: apiToken, saleSource: payload.SaleSource, orderID: payload.OrderID}
+		plan.batchByKey[key] = batch
+		plan.batches = append(plan.batches, batch)
+	}
+	plan.refs = append(plan.refs, shipmentRef{batch: batch, index: len(batch.payloads), line: line})
+	batch.payloads = append(batch.payloads, payload)
+	batch.lines = append(batch.lines, line)
+}
+
+// fail records payload from the one-based source line as failed with err, in its place among the plan's refs,
+// without adding it to a batch that will be sent.
+func (plan *shipmentPlan) fail(payload ShipmentPayload, err error, line int) {
+	batch := &shipmentBatch{
+		payloads: []ShipmentPayload{payload},
+		lines:    []int{line},
+		outcomes: []shipmentOutcome{{err: err}},
+	}
+	plan.refs = append(plan.refs, shipmentRef{batch: batch, line: line})
+}
+
+// submitShipmentBatches sends every batch, sequentially or with up to the configured number of workers per sale source.
+// Each batch's per-shipment results are stored in its outcomes field.
+func submitShipmentBatches(ctx context.Context, client FaireClientInterface, batches []*shipmentBatch, options ShipmentProcessOptions) {
+	var total int
+	for _, batch := range batches {
+		total += len(batch.payloads)
+	}
+	reporter := &shipmentProgressReporter{onProgress: options.OnProgress, progress: ShipmentProgress{Total: total}}
+	reporter.start()
+
+	if options.Workers < 1 || submitsSequentially(client) {
+		for _, batch := range batches {
+			batch.submit(ctx, client, options)
+			reporter.batchDone(batch)
 		}
-		addErr := client.AddShipment(payload, apiToken)
-		if addErr != nil {
-			// Preserve the API error in the result so the GUI can show the user which shipment failed.
-			payload.ErrorMsg = addErr.Error()
-			failed = append(failed, payload)
-		} else {
-			processed = append(processed, payload)
+		return
+	}
+
+	batchesBySaleSource := make(map[string][]*shipmentBatch)
+	var saleSources []string
+	for _, batch := range batches {
+		saleSource := strings.ToUpper(batch.saleSource)
+		if _, exists := batchesBySaleSource[saleSource]; !exists {
+			saleSources = append(saleSources, saleSource)
+		}
+		batchesBySaleSource[saleSource] = append(batchesBySaleSource[saleSource], batch)
+	}
+
+	var wg sync.WaitGroup
+	for _, saleSource := range saleSources {
+		queue := make(chan *shipmentBatch)
+		for i := 0; i < options.workersFor(saleSource); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for batch := range queue {
+					batch.submit(ctx, client, options)
+					reporter.batchDone(batch)
+				}
+			}()
+		}
+		go func(sourceBatches []*shipmentBatch) {
+			for _, batch := range sourceBatches {
+				queue <- batch
+			}
+			close(queue)
+		}(batchesBySaleSource[saleSource])
+	}
+	wg.Wait()
+}
+
+// sequentialClient is implemented by clients whose behavior depends on the order of their shipment requests.
+type sequentialClient interface {
+	submitsSequentially() bool
+}
+
+// submitsSequentially reports whether client needs its shipment requests sent one at a time in plan order.
+func submitsSequentially(client FaireClientInterface) bool {
+	sequential, ok := client.(sequentialClient)
+	return ok && sequential.submitsSequentially()
+}
+
+// workersFor returns the number of concurrent requests allowed for saleSource.
+func (options ShipmentProcessOptions) workersFor(saleSource string) int {
+	workers := options.Workers
+	for source, sourceWorkers := range options.SaleSourceWorkers {
+		if strings.EqualFold(source, saleSource) {
+			workers = sourceWorkers
+		}
+	}
+	if workers < 1 {
+		return 1
+	}
+	return workers
+}
+
+// shipmentProgressReporter serializes progress callbacks from concurrent workers.
+type shipmentProgressReporter struct {
+	mu         sync.Mutex
+	onProgress func(ShipmentProgress)
+	progress   ShipmentProgress
+}
+
+// start reports the total before any shipment is submitted.
+func (r *shipmentProgressReporter) start() {
+	if r.onProgress != nil {
+		r.onProgress(r.progress)
+	}
+}
+
+// batchDone reports each shipment in batch as complete.
+func (r *shipmentProgressReporter) batchDone(batch *shipmentBatch) {
+	if r.onProgress == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, payload := range batch.payloads {
+		r.progress.Done++
+		switch outcome := batch.outcomes[i]; {
+		case outcome.err != nil:
+			payload.ErrorMsg = outcome.err.Error()
+			payload.FailureKind = classifyFailure(outcome.err)
+			r.progress.Failed++
+			r.progress.Outcome = failedOutcome(payload)
+		case outcome.alreadyPresent:
+			r.progress.AlreadyPresent++
+			r.progress.Outcome = RunOutcomeAlreadyPresent
+		case outcome.duplicateOf != "":
+			payload.ErrorMsg = "duplicate of " + outcome.duplicateOf
+			r.progress.Duplicates++
+			r.progress.Outcome = RunOutcomeDuplicate
+		default:
+			payload.Created = outcome.created
+			r.progress.Succeeded++
+			r.progress.Outcome = RunOutcomeProcessed
+		}
+		r.progress.Payload = payload
+		r.onProgress(r.progress)
+	}
+}
+
+// shipmentBatchKey identifies shipments that can share one Faire request.
+type shipmentBatchKey struct {
+	apiToken string
+	orderID  string
+}
+
+// shipmentBatch holds the payloads sent in one shipment request and their outcomes.
+type shipmentBatch struct {
+	apiToken   string
+	saleSource string
+	orderID    string
+	payloads   []ShipmentPayload
+	lines      []int // The source line of each payload, or zero when it did not come from an export.
+	outcomes   []shipmentOutcome
+	// duplicates maps the index of each payload that repeats an earlier payload's tracking code to that payload's index.
+	duplicates map[int]int
+}
+
+// shipmentOutcome is the result of processing one payload in a batch.
+type shipmentOutcome struct {
+	err            error
+	alreadyPresent bool
+	created        *OrderShipment // The shipment Faire reported creating, if it could be matched.
+	warning        string         // A problem with the order that did not stop the shipment from being posted.
+	duplicateOf    string         // The earlier row this payload repeats, when it was not posted for that reason.
+}
+
+// shipmentRef locates one CSV row's payload within its batch.
+type shipmentRef struct {
+	batch *shipmentBatch
+	index int
+	line  int
+}
+
+// submit posts the batch's payloads, first checking the order's state and skipping tracking codes already on the
+// order when options request it. Once ctx is canceled the payloads fail without being sent.
+func (batch *shipmentBatch) submit(ctx context.Context, client FaireClientInterface, options ShipmentProcessOptions) {
+	batch.outcomes = make([]shipmentOutcome, len(batch.payloads))
+	batch.duplicates = nil
+	defer batch.resolveDuplicates()
+	pending := make([]int, 0, len(batch.payloads))
+	for i := range batch.payloads {
+		pending = append(pending, i)
+	}
+	if err := ctx.Err(); err != nil {
+		batch.fail(pending, fmt.Errorf("%w: %w", errNotSent, err))
+		return
+	}
+
+	if options.SkipExistingShipments || options.OrderStateCheck != OrderStateCheckOff {
+		order, err := fetchOrder(ctx, client, batch.orderID, batch.apiToken)
+		if err != nil && ctx.Err() != nil {
+			// The run was canceled during the order lookup, before anything was posted.
+			batch.fail(pending, fmt.Errorf("%w: %w", errNotSent, err))
+			return
+		}
+		if err != nil {
+			// Posting without knowing the order's shipments or state could create duplicates or ship a canceled
+			// order, so the whole batch fails.
+			batch.fail(pending, fmt.Errorf("check order before posting: %w", err))
+			return
+		}
+		if problem := orderShippingProblem(order); problem != "" {
+			switch options.OrderStateCheck {
+			case OrderStateCheckBlock:
+				batch.fail(pending, fmt.Errorf("%s; %w", problem, errOrderNotShippable))
+				return
+			case OrderStateCheckWarn:
+				for _, i := range pending {
+					batch.outcomes[i].warning = problem
+				}
+			}
+		}
+		if options.SkipExistingShipments {
+			existing := orderTrackingCodes(order)
+			batch.duplicates = batch.findDuplicates(existing)
+			pending = pending[:0]
+			for i, payload := range batch.payloads {
+				if _, duplicate := batch.duplicates[i]; duplicate {
+					continue
+				}
+				trackingCode := normalizeTrackingCode(payload.TrackingCode)
+				if _, present := existing[trackingCode]; present && trackingCode != "" {
+					batch.outcomes[i].alreadyPresent = true
+					continue
+				}
+				pending = append(pending, i)
+			}
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+	if err := ctx.Err(); err != nil {
+		batch.fail(pending, fmt.Errorf("%w: %w", errNotSent, err))
+		return
+	}
+
+	toPost := make([]ShipmentPayload, 0, len(pending))
+	for _, i := range pending {
+		toPost = append(toPost, batch.payloads[i])
+	}
+	created, err := client.AddShipments(ctx, toPost, batch.apiToken)
+	if err != nil {
+		batch.fail(pending, err)
+		return
+	}
+	for i, shipment := range matchCreatedShipments(toPost, created) {
+		batch.outcomes[pending[i]].created = shipment
+	}
+}
+
+// matchCreatedShipments pairs each posted payload with the shipment Faire created for it, matching by tracking code
+// and falling back to response order when Faire returned one shipment per payload. The fallback only uses shipments
+// without a tracking code, so a payload is never given the ID of another row's shipment. Unmatched payloads get nil.
+func matchCreatedShipments(payloads []ShipmentPayload, created []OrderShipment) []*OrderShipment {
+	matches := make([]*OrderShipment, len(payloads))
+	used := make([]bool, len(created))
+	for i, payload := range payloads {
+		trackingCode := normalizeTrackingCode(payload.TrackingCode)
+		for j := range created {
+			if !used[j] && trackingCode != "" && normalizeTrackingCode(created[j].TrackingCode) == trackingCode {
+				matches[i] = &created[j]
+				used[j] = true
+				break
+			}
+		}
+	}
+	if len(created) == len(payloads) {
+		for i := range matches {
+			if matches[i] == nil && !used[i] && normalizeTrackingCode(created[i].TrackingCode) == "" {
+				matches[i] = &created[i]
+				used[i] = true
+			}
+		}
+	}
+	return matches
+}
+
+// findDuplicates returns the payloads that repeat the tracking code of an earlier payload in the batch, mapped to the
+// index of that earlier payload. Codes in existing are already on the order, so repeats of them are not included.
+func (batch *shipmentBatch) findDuplicates(existing map[string]struct{}) map[int]int {
+	var duplicates map[int]int
+	first := make(map[string]int, len(batch.payloads))
+	for i, payload := range batch.payloads {
+		trackingCode := normalizeTrackingCode(payload.TrackingCode)
+		if _, present := existing[trackingCode]; present || trackingCode == "" {
+			continue
+		}
+		if earlier, seen := first[trackingCode]; seen {
+			if duplicates == nil {
+				duplicates = make(map[int]int)
+			}
+			duplicates[i] = earlier
+			continue
+		}
+		first[trackingCode] = i
+	}
+	return duplicates
+}
+
+// resolveDuplicates gives each duplicate payload the result of the payload it repeats: it fails when that payload
+// failed and is otherwise marked as a duplicate that was not posted.
+func (batch *shipmentBatch) resolveDuplicates() {
+	for i, earlier := range batch.duplicates {
+		if err := batch.outcomes[earlier].err; err != nil {
+			batch.outcomes[i].err = fmt.Errorf("duplicate of %s, which failed: %w", batch.describe(earlier), err)
+			continue
 		}
+		batch.outcomes[i].duplicateOf = batch.describe(earlier)
 	}
-	return
+}
+
+// describe names the payload at index for messages, by its source line when it has one.
+func (batch *shipmentBatch) describe(index int) string {
+	if line := batch.lines[index]; line > 0 {
+		return fmt.Sprintf("line %d", line)
+	}
+	return fmt.Sprintf("the earlier shipment with tracking code %s", batch.payloads[index].TrackingCode)
+}
+
+// fail records err as the outcome of the payloads at indices.
+func (batch *shipmentBatch) fail(indices []int, err error) {
+	for _, i := range indices {
+		batch.outcomes[i].err = err
+	}
+}
+
+// fetchOrder retrieves and decodes orderID.
+func fetchOrder(ctx context.Context, client OrderClient, orderID, apiToken string) (Order, error) {
+	var order Order
+	response, err := client.GetOrderByID(ctx, orderID, apiToken)
+	if err != nil {
+		return order, fmt.Errorf("get order %q: %w", orderID, err)
+	}
+	if err := json.Unmarshal(response, &order); err != nil {
+		return order, fmt.Errorf("parse order %q: %w", orderID, err)
+	}
+	return order, nil
+}
+
+// orderTrackingCodes returns the normalized tracking codes already attached to order.
+func orderTrackingCodes(order Order) map[string]struct{} {
+	trackingCodes := make(map[string]struct{}, len(order.Shipments))
+	for _, shipment := range order.Shipments {
+		trackingCodes[normalizeTrackingCode(shipment.TrackingCode)] = struct{}{}
+	}
+	return trackingCodes
+}
+
+// normalizeTrackingCode returns trackingCode in the form used to compare shipments.
+func normalizeTrackingCode(trackingCode string) string {
+	return strings.ToUpper(strings.TrimSpace(trackingCode))
 }
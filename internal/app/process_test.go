@@ -1,8 +1,12 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestProcessShipments_MockClient(t *testing.T) {
@@ -20,22 +24,14 @@ func TestProcessShipments_MockClient(t *testing.T) {
 0083510,ATG32GC3XX,1Z972Y3Y0312933410,7/17/2025,17.79,UPS,Prepaid,0090671,BSC
 0083511,ATG32GC3XY,1Z972Y3Y0312933411,7/18/2025,18.00,UPS,Prepaid,0090671,BSC
 `
-	tmpFile, err := os.CreateTemp("", "test_shipments_*.csv")
-	if err != nil {
-		t.Fatalf("failed to create temp file: %v", err)
-	}
-	defer os.Remove(tmpFile.Name())
-	if _, err := tmpFile.WriteString(csvContent); err != nil {
-		t.Fatalf("failed to write to temp file: %v", err)
-	}
-	tmpFile.Close()
+	path := writeShipmentsCSV(t, csvContent)
 
 	// Simulate one failure on the second call
 	mockClient := &MockFaireClient{
 		FailOnCall: map[int]bool{2: true},
 	}
 
-	processed, failed, err := ProcessShipments(tmpFile.Name(), mockClient)
+	processed, failed, err := ProcessShipments(context.Background(), path, mockClient)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -49,3 +45,320 @@ func TestProcessShipments_MockClient(t *testing.T) {
 
 	// Shipment results are returned directly to the caller; no log file is created.
 }
+
+func TestProcessShipments_GroupsShipmentsByOrder(t *testing.T) {
+	t.Setenv("BSC_API_TOKEN", "dummy-token")
+	t.Setenv("SMD_API_TOKEN", "dummy-token")
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,MULTIBOX,1Z0000000000000001,5.00,UPS,Prepaid,0090671,BSC
+DOC2,SINGLE,1Z0000000000000002,6.00,UPS,Prepaid,0090671,SM
+DOC3,MULTIBOX,1Z0000000000000003,7.00,UPS,Prepaid,0090671,BSC
+`
+	path := writeShipmentsCSV(t, csvContent)
+
+	// The first request carries both MULTIBOX shipments, so failing it fails both rows.
+	mockClient := &MockFaireClient{FailOnCall: map[int]bool{1: true}}
+	processed, failed, err := ProcessShipments(context.Background(), path, mockClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if mockClient.CallCount != 2 {
+		t.Errorf("expected 2 shipment requests, got %d", mockClient.CallCount)
+	}
+	if len(processed) != 1 || processed[0].OrderID != "bo_single" {
+		t.Errorf("expected bo_single to be processed, got %+v", processed)
+	}
+	if len(failed) != 2 || failed[0].TrackingCode != "1Z0000000000000001" || failed[1].TrackingCode != "1Z0000000000000003" {
+		t.Errorf("expected both MULTIBOX shipments to fail in CSV order, got %+v", failed)
+	}
+}
+
+func TestProcessShipmentsWithOptions_ConcurrentPreservesOrder(t *testing.T) {
+	t.Setenv("BSC_API_TOKEN", "dummy-token")
+	t.Setenv("SMD_API_TOKEN", "dummy-token")
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,ORDER1,TRACK1,1.00,UPS,Prepaid,0090671,BSC
+DOC2,ORDER2,TRACK2,2.00,UPS,Prepaid,0090671,SM
+DOC3,ORDER3,TRACK3,3.00,UPS,Prepaid,0090671,BSC
+DOC4,ORDER4,TRACK4,4.00,UPS,Prepaid,0090671,SM
+DOC5,ORDER5,TRACK5,5.00,UPS,Prepaid,0090671,BSC
+`
+	path := writeShipmentsCSV(t, csvContent)
+
+	var reports []ShipmentProgress
+	results, err := ProcessShipmentsWithOptions(context.Background(), path, &MockFaireClient{}, ShipmentProcessOptions{
+		Workers:           3,
+		SaleSourceWorkers: map[string]int{"sm": 1},
+		OnProgress:        func(p ShipmentProgress) { reports = append(reports, p) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results.Failed) != 0 {
+		t.Fatalf("expected no failures, got %+v", results.Failed)
+	}
+
+	for i, payload := range results.Processed {
+		if want := fmt.Sprintf("TRACK%d", i+1); payload.TrackingCode != want {
+			t.Errorf("processed[%d].TrackingCode = %q, want %q", i, payload.TrackingCode, want)
+		}
+	}
+	if len(reports) != 6 {
+		t.Fatalf("expected 6 progress reports, got %d", len(reports))
+	}
+	if first := reports[0]; first.Done != 0 || first.Total != 5 {
+		t.Errorf("initial progress = %+v, want 0 of 5", first)
+	}
+	if last := reports[len(reports)-1]; last.Done != 5 || last.Succeeded != 5 || last.Outcome != RunOutcomeProcessed {
+		t.Errorf("final progress = %+v, want 5 done and succeeded", last)
+	}
+}
+
+func TestProcessShipmentsWithOptions_MockFailuresFollowPlanOrder(t *testing.T) {
+	t.Setenv("BSC_API_TOKEN", "dummy-token")
+	t.Setenv("SMD_API_TOKEN", "dummy-token")
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,ORDER1,TRACK1,1.00,UPS,Prepaid,0090671,BSC
+DOC2,ORDER2,TRACK2,2.00,UPS,Prepaid,0090671,SM
+DOC3,ORDER3,TRACK3,3.00,UPS,Prepaid,0090671,BSC
+DOC4,ORDER4,TRACK4,4.00,UPS,Prepaid,0090671,SM
+`
+	path := writeShipmentsCSV(t, csvContent)
+
+	// Even with several workers, the configured failures land on the second and fourth requests in CSV order.
+	mockClient := &MockFaireClient{FailOnCall: map[int]bool{2: true, 4: true}}
+	results, err := ProcessShipmentsWithOptions(context.Background(), path, mockClient, ShipmentProcessOptions{Workers: 4})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results.Failed) != 2 || results.Failed[0].TrackingCode != "TRACK2" || results.Failed[1].TrackingCode != "TRACK4" {
+		t.Errorf("failed = %+v, want TRACK2 and TRACK4", results.Failed)
+	}
+}
+
+func TestProcessShipmentsWithOptions_SkipsExistingTrackingCodes(t *testing.T) {
+	t.Setenv("BSC_API_TOKEN", "dummy-token")
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,ORDER1,TRACK1,1.00,UPS,Prepaid,0090671,BSC
+DOC2,ORDER2,TRACK2,2.00,UPS,Prepaid,0090671,BSC
+DOC3,ORDER2,TRACK3,3.00,UPS,Prepaid,0090671,BSC
+`
+	path := writeShipmentsCSV(t, csvContent)
+
+	// ORDER2 already has TRACK2, and the first run's ORDER1 request fails.
+	mockClient := &MockFaireClient{
+		FailOnCall:       map[int]bool{1: true},
+		AutoCreateOrders: true,
+		Orders: []Order{{
+			ID:        "bo_order2",
+			DisplayID: "ORDER2",
+			State:     OrderStateNew,
+			Shipments: []OrderShipment{{TrackingCode: "track2"}},
+		}},
+	}
+	options := ShipmentProcessOptions{SkipExistingShipments: true}
+
+	first, err := ProcessShipmentsWithOptions(context.Background(), path, mockClient, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(first.Failed) != 1 || first.Failed[0].TrackingCode != "TRACK1" {
+		t.Errorf("first run failed = %+v, want TRACK1", first.Failed)
+	}
+	if len(first.AlreadyPresent) != 1 || first.AlreadyPresent[0].TrackingCode != "TRACK2" {
+		t.Errorf("first run already present = %+v, want TRACK2", first.AlreadyPresent)
+	}
+	if len(first.Processed) != 1 || first.Processed[0].TrackingCode != "TRACK3" {
+		t.Errorf("first run processed = %+v, want TRACK3", first.Processed)
+	} else if created := first.Processed[0].Created; created == nil || created.ID == "" || created.TrackingCode != "TRACK3" {
+		t.Errorf("first run processed shipment created = %+v, want the mock shipment for TRACK3", created)
+	}
+	if len(first.Failed) == 1 && first.Failed[0].Created != nil {
+		t.Errorf("failed shipment has a created shipment %+v", first.Failed[0].Created)
+	}
+
+	// Re-running the same CSV posts only the shipment that failed before.
+	second, err := ProcessShipmentsWithOptions(context.Background(), path, mockClient, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(second.Failed) != 0 {
+		t.Errorf("second run failed = %+v, want none", second.Failed)
+	}
+	if len(second.Processed) != 1 || second.Processed[0].TrackingCode != "TRACK1" {
+		t.Errorf("second run processed = %+v, want TRACK1", second.Processed)
+	}
+	if len(second.AlreadyPresent) != 2 {
+		t.Errorf("second run already present = %+v, want TRACK2 and TRACK3", second.AlreadyPresent)
+	}
+}
+
+func TestProcessShipmentsWithOptions_ReportsInFileDuplicates(t *testing.T) {
+	t.Setenv("BSC_API_TOKEN", "dummy-token")
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,ORDER1,TRACK1,1.00,UPS,Prepaid,0090671,BSC
+DOC2,ORDER1,track1,1.00,UPS,Prepaid,0090671,BSC
+`
+	path := writeShipmentsCSV(t, csvContent)
+
+	mockClient := &MockFaireClient{FailOnCall: map[int]bool{1: true}, AutoCreateOrders: true}
+	options := ShipmentProcessOptions{SkipExistingShipments: true}
+
+	// The first row's request fails, so its duplicate fails with it rather than being reported as already present.
+	first, err := ProcessShipmentsWithOptions(context.Background(), path, mockClient, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(first.Failed) != 2 || len(first.AlreadyPresent) != 0 || len(first.Duplicates) != 0 {
+		t.Fatalf("first run = %+v, want both rows failed", first)
+	}
+	if got := first.Failed[1].ErrorMsg; !strings.HasPrefix(got, "duplicate of line 2, which failed: ") {
+		t.Errorf("duplicate error = %q, want it to name line 2 and its failure", got)
+	}
+
+	second, err := ProcessShipmentsWithOptions(context.Background(), path, mockClient, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(second.Processed) != 1 || second.Processed[0].TrackingCode != "TRACK1" {
+		t.Errorf("second run processed = %+v, want TRACK1", second.Processed)
+	}
+	if len(second.Duplicates) != 1 || second.Duplicates[0].ErrorMsg != "duplicate of line 2" || len(second.AlreadyPresent) != 0 {
+		t.Errorf("second run duplicates = %+v, already present = %+v, want the second row as a duplicate of line 2",
+			second.Duplicates, second.AlreadyPresent)
+	}
+}
+
+func TestProcessShipmentsWithOptions_ChecksOrderState(t *testing.T) {
+	t.Setenv("BSC_API_TOKEN", "dummy-token")
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,OPEN,1Z999AA10123456784,1.00,UPS,Prepaid,0090671,BSC
+DOC2,CANCELLED,1Z999AA10123456784,2.00,UPS,Prepaid,0090671,BSC
+DOC3,CANCELLING,1Z999AA10123456784,3.00,UPS,Prepaid,0090671,BSC
+`
+	path := writeShipmentsCSV(t, csvContent)
+
+	orders := []Order{
+		{ID: "bo_open", DisplayID: "OPEN", State: OrderStateNew},
+		{ID: "bo_cancelled", DisplayID: "CANCELLED", State: "CANCELED"},
+		{ID: "bo_cancelling", DisplayID: "CANCELLING", State: OrderStateNew, HasPendingRetailerCancellationRequest: true},
+	}
+
+	blocked, err := ProcessShipmentsWithOptions(context.Background(), path, &MockFaireClient{Orders: orders}, ShipmentProcessOptions{OrderStateCheck: OrderStateCheckBlock})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(blocked.Processed) != 1 || blocked.Processed[0].OrderID != "bo_open" {
+		t.Errorf("blocked run processed = %+v, want only OPEN", blocked.Processed)
+	}
+	if len(blocked.Failed) != 2 || blocked.Failed[0].ErrorMsg != "order is CANCELED; shipment not posted" || blocked.Failed[1].ErrorMsg != "retailer has requested cancellation of the order; shipment not posted" ||
+		blocked.Failed[0].FailureKind != FailureOrderState {
+		t.Errorf("blocked run failed = %+v, want CANCELLED and CANCELLING", blocked.Failed)
+	}
+
+	warned, err := ProcessShipmentsWithOptions(context.Background(), path, &MockFaireClient{Orders: orders}, ShipmentProcessOptions{OrderStateCheck: OrderStateCheckWarn})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(warned.Processed) != 3 || len(warned.Failed) != 0 {
+		t.Errorf("warned run = %d processed and %d failed, want 3 and 0", len(warned.Processed), len(warned.Failed))
+	}
+	if len(warned.Warnings) != 2 || warned.Warnings[0].Line != 3 || warned.Warnings[1].Message != "retailer has requested cancellation of the order (order CANCELLING)" {
+		t.Errorf("warned run warnings = %+v, want lines 3 and 4", warned.Warnings)
+	}
+
+	if _, err := ParseOrderStateCheck("sometimes"); err == nil {
+		t.Errorf("ParseOrderStateCheck() accepted an unknown value")
+	}
+}
+
+func TestProcessShipmentsWithOptions_StopsWhenCanceled(t *testing.T) {
+	t.Setenv("BSC_API_TOKEN", "dummy-token")
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,ORDER1,1Z999AA10123456784,1.00,UPS,Prepaid,0090671,BSC
+DOC2,ORDER2,1Z999AA10123456784,2.00,UPS,Prepaid,0090671,BSC
+DOC3,ORDER3,1Z999AA10123456784,3.00,UPS,Prepaid,0090671,BSC
+`
+	path := writeShipmentsCSV(t, csvContent)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mockClient := &MockFaireClient{}
+	results, err := ProcessShipmentsWithOptions(ctx, path, mockClient, ShipmentProcessOptions{
+		// Cancel as soon as the first shipment completes, as the GUI's Cancel button would.
+		OnProgress: func(p ShipmentProgress) {
+			if p.Done == 1 {
+				cancel()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !results.Canceled {
+		t.Errorf("results.Canceled = false, want true")
+	}
+	if len(results.Processed) != 1 || results.Processed[0].OrderID != "bo_order1" {
+		t.Errorf("processed = %+v, want only ORDER1", results.Processed)
+	}
+	if len(results.Failed) != 2 || results.Failed[0].ErrorMsg != "not sent: context canceled" {
+		t.Errorf("failed = %+v, want ORDER2 and ORDER3 not sent", results.Failed)
+	}
+	if mockClient.CallCount != 1 {
+		t.Errorf("mock client received %d calls, want 1", mockClient.CallCount)
+	}
+}
+
+func TestProcessShipmentsWithOptions_CancelInFlightIsOutcomeUnknown(t *testing.T) {
+	t.Setenv("BSC_API_TOKEN", "dummy-token")
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,ORDER1,1Z999AA10123456784,1.00,UPS,Prepaid,0090671,BSC
+DOC2,ORDER2,1Z999AA10123456784,2.00,UPS,Prepaid,0090671,BSC
+`
+	path := writeShipmentsCSV(t, csvContent)
+
+	// Cancel while the first request is still waiting on the mock's simulated latency.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(mockLatency/3, cancel)
+	results, err := ProcessShipmentsWithOptions(ctx, path, &MockFaireClient{}, ShipmentProcessOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results.Failed) != 2 {
+		t.Fatalf("failed = %+v, want both shipments", results.Failed)
+	}
+	if kind := results.Failed[0].FailureKind; kind != FailureOutcomeUnknown {
+		t.Errorf("in-flight shipment kind = %q, want %q", kind, FailureOutcomeUnknown)
+	}
+	if kind := results.Failed[1].FailureKind; kind != FailureNotSent {
+		t.Errorf("unsent shipment kind = %q, want %q", kind, FailureNotSent)
+	}
+}
+
+func TestProcessShipmentsWithOptions_SortsSkippedRowsByLine(t *testing.T) {
+	t.Setenv("BSC_API_TOKEN", "dummy-token")
+	t.Setenv("SMD_API_TOKEN", "")
+	// Line 2 is skipped by the plan for lacking a token and line 3 by the parser, so the two lists interleave.
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,ORDER1,TRACK1,1.00,UPS,Prepaid,0090671,SM
+DOC2,ORDER2,TRACK2,2.00,UPS,Prepaid,0000001,BSC
+DOC3,ORDER3,TRACK3,3.00,UPS,Prepaid,0090671,BSC
+`
+	path := writeShipmentsCSV(t, csvContent)
+
+	results, err := ProcessShipmentsWithOptions(context.Background(), path, &MockFaireClient{AutoCreateOrders: true}, ShipmentProcessOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	preview, err := PreviewShipments(context.Background(), path, &MockFaireClient{}, ShipmentPreviewOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, skipped := range map[string][]SkippedRow{"process": results.SkippedRows, "preview": preview.SkippedRows} {
+		if len(skipped) != 2 || skipped[0].Line != 2 || skipped[0].Kind != SkipMissingToken || skipped[1].Line != 3 {
+			t.Errorf("%s skipped rows = %+v, want the missing-token line 2 before line 3", name, skipped)
+		}
+	}
+}
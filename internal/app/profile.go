@@ -0,0 +1,258 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// ShipmentCSVProfile describes how to read one shipping system's export: which columns hold each shipment field
+// and which rows count as Faire shipments.
+type ShipmentCSVProfile struct {
+	Name    string          `json:"name"`
+	Columns ShipmentColumns `json:"columns"`
+	// FaireCustomerIDs lists the recipient customer IDs used for Faire orders; rows for other customers are skipped.
+	// An empty list accepts every row and makes the customer ID column optional.
+	FaireCustomerIDs []string `json:"faire_customer_ids"`
+	// SaleSources lists the accepted values of the sale source column; rows with other values are skipped.
+	SaleSources []string `json:"sale_sources"`
+	// Carriers maps shipping-system carrier names to Faire carrier codes, ahead of the built-in carrier table.
+	Carriers map[string]string `json:"carriers"`
+	// Sheet names the worksheet to read from XLSX exports; empty uses the first sheet containing the columns.
+	Sheet string `json:"sheet"`
+	// ShippingTypes maps billing type column values, matched case-insensitively, to Faire shipping types.
+	ShippingTypes map[string]string `json:"shipping_types"`
+	// DefaultShippingType is used for rows whose billing type is not in ShippingTypes.
+	DefaultShippingType string `json:"default_shipping_type"`
+	// PackageCharges divides the shipping charge of an order listed on several rows, one per package.
+	// Empty keeps each row's charge as listed.
+	PackageCharges ChargeAllocation `json:"package_charges"`
+	// PackageChargesRepeated is set when the export lists an order's whole charge on each of its package rows, or only
+	// on the first; otherwise each row's charge is its own and PackageCharges divides their sum.
+	PackageChargesRepeated bool `json:"package_charges_repeated"`
+	// POCharges divides a row's shipping charge among the orders when its PO cell lists several POs.
+	// Empty charges every order the full amount.
+	POCharges ChargeAllocation `json:"po_charges"`
+}
+
+// Faire shipping types accepted by the shipment endpoint.
+const (
+	// ShippingTypeShipOnYourOwn marks a shipment the maker sent with its own carrier account.
+	ShippingTypeShipOnYourOwn = "SHIP_ON_YOUR_OWN"
+	// ShippingTypeShipWithFaire marks a shipment sent on a label purchased through Faire.
+	ShippingTypeShipWithFaire = "SHIP_WITH_FAIRE"
+)
+
+// FaireShippingTypes lists the shipping types accepted by Faire's shipment endpoint.
+var FaireShippingTypes = []string{ShippingTypeShipOnYourOwn, ShippingTypeShipWithFaire}
+
+// ShipmentColumns maps shipment fields to export header names.
+// SourceDocumentKey and BillingType may be empty when an export has no such column.
+type ShipmentColumns struct {
+	SourceDocumentKey string `json:"source_document_key"`
+	PONumbers         string `json:"po_numbers"`
+	TrackingCode      string `json:"tracking_code"`
+	ShippingCharges   string `json:"shipping_charges"`
+	Carrier           string `json:"carrier"`
+	BillingType       string `json:"billing_type"`
+	CustomerID        string `json:"customer_id"`
+	SaleSource        string `json:"sale_source"`
+}
+
+// DefaultShipmentCSVProfile matches the shipping-system export the application was built for.
+var DefaultShipmentCSVProfile = ShipmentCSVProfile{
+	Name: "default",
+	Columns: ShipmentColumns{
+		SourceDocumentKey: "Source Document Key",
+		PONumbers:         "PO Numbers",
+		TrackingCode:      "Master Tracking #",
+		ShippingCharges:   "Shipment Charges Applied Total",
+		Carrier:           "Ship Carrier Name",
+		BillingType:       "Billing Type",
+		CustomerID:        "Recipient Customer ID",
+		SaleSource:        "Sale Source (UDF)",
+	},
+	FaireCustomerIDs:    []string{"0090671"},
+	SaleSources:         []string{"21", "ASC", "BJP", "BSC", "GTG", "OAT", "SM"},
+	DefaultShippingType: ShippingTypeShipOnYourOwn,
+	POCharges:           ChargesSplitEvenly,
+}
+
+// LoadShipmentCSVProfile reads a JSON profile from path.
+// Fields omitted from the file keep their DefaultShipmentCSVProfile values.
+func LoadShipmentCSVProfile(path string) (ShipmentCSVProfile, error) {
+	profile := DefaultShipmentCSVProfile.clone()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return profile, fmt.Errorf("read shipment profile: %w", err)
+	}
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return profile, fmt.Errorf("parse shipment profile %q: %w", path, err)
+	}
+	if err := profile.validate(); err != nil {
+		return profile, fmt.Errorf("shipment profile %q: %w", path, err)
+	}
+	return profile, nil
+}
+
+// ShipmentCSVProfileFromEnv loads the profile named by FAIRE_SHIPMENT_PROFILE, or returns the default profile when it is unset.
+func ShipmentCSVProfileFromEnv() (ShipmentCSVProfile, error) {
+	_ = godotenv.Load()
+	path := strings.TrimSpace(os.Getenv("FAIRE_SHIPMENT_PROFILE"))
+	if path == "" {
+		return DefaultShipmentCSVProfile.clone(), nil
+	}
+	return LoadShipmentCSVProfile(path)
+}
+
+// profileOrDefault returns *profile, or DefaultShipmentCSVProfile when profile is nil.
+func profileOrDefault(profile *ShipmentCSVProfile) ShipmentCSVProfile {
+	if profile == nil {
+		return DefaultShipmentCSVProfile
+	}
+	return *profile
+}
+
+// requiredHeaders returns the header names that every export read with the profile must contain.
+func (p ShipmentCSVProfile) requiredHeaders() []string {
+	headers := []string{
+		p.Columns.SourceDocumentKey,
+		p.Columns.PONumbers,
+		p.Columns.TrackingCode,
+		p.Columns.ShippingCharges,
+		p.Columns.Carrier,
+		p.Columns.BillingType,
+		p.Columns.CustomerID,
+		p.Columns.SaleSource,
+	}
+	required := make([]string, 0, len(headers))
+	for _, header := range headers {
+		if header != "" {
+			required = append(required, header)
+		}
+	}
+	return required
+}
+
+// validate rejects profiles that omit a column needed to build a shipment.
+func (p ShipmentCSVProfile) validate() error {
+	columns := map[string]string{
+		"po_numbers":       p.Columns.PONumbers,
+		"tracking_code":    p.Columns.TrackingCode,
+		"shipping_charges": p.Columns.ShippingCharges,
+		"carrier":          p.Columns.Carrier,
+		"sale_source":      p.Columns.SaleSource,
+	}
+	if len(p.FaireCustomerIDs) > 0 {
+		columns["customer_id"] = p.Columns.CustomerID
+	}
+	for field, header := range columns {
+		if strings.TrimSpace(header) == "" {
+			return fmt.Errorf("column %s must name a header", field)
+		}
+	}
+	if len(p.SaleSources) == 0 {
+		return fmt.Errorf("sale_sources must list at least one sale source")
+	}
+	if !isFaireShippingType(p.DefaultShippingType) {
+		return fmt.Errorf("default_shipping_type %q is not a Faire shipping type (%s)", p.DefaultShippingType, strings.Join(FaireShippingTypes, ", "))
+	}
+	if err := p.PackageCharges.validate(); err != nil {
+		return fmt.Errorf("package_charges: %w", err)
+	}
+	if err := p.POCharges.validate(); err != nil {
+		return fmt.Errorf("po_charges: %w", err)
+	}
+	// Billing types are matched ignoring case and surrounding spaces, so two names that differ only in those would
+	// make the shipping type depend on map order.
+	billingTypes := make([]string, 0, len(p.ShippingTypes))
+	for billingType := range p.ShippingTypes {
+		billingTypes = append(billingTypes, billingType)
+	}
+	sort.Strings(billingTypes)
+	seen := make(map[string]string, len(billingTypes))
+	for _, billingType := range billingTypes {
+		shippingType := p.ShippingTypes[billingType]
+		if !isFaireShippingType(shippingType) {
+			return fmt.Errorf("shipping_types[%q] %q is not a Faire shipping type (%s)", billingType, shippingType, strings.Join(FaireShippingTypes, ", "))
+		}
+		key := strings.ToLower(strings.TrimSpace(billingType))
+		if other, exists := seen[key]; exists {
+			return fmt.Errorf("shipping_types lists both %q and %q; billing types are matched ignoring case and spaces", other, billingType)
+		}
+		seen[key] = billingType
+	}
+	return nil
+}
+
+// shippingType returns the Faire shipping type for a row with billingType.
+func (p ShipmentCSVProfile) shippingType(billingType string) string {
+	billingType = strings.TrimSpace(billingType)
+	for name, shippingType := range p.ShippingTypes {
+		if strings.EqualFold(strings.TrimSpace(name), billingType) {
+			return strings.ToUpper(strings.TrimSpace(shippingType))
+		}
+	}
+	if p.DefaultShippingType == "" {
+		return ShippingTypeShipOnYourOwn
+	}
+	return strings.ToUpper(strings.TrimSpace(p.DefaultShippingType))
+}
+
+// isFaireShippingType reports whether shippingType, ignoring case and surrounding spaces, is one of FaireShippingTypes.
+func isFaireShippingType(shippingType string) bool {
+	for _, known := range FaireShippingTypes {
+		if strings.EqualFold(known, strings.TrimSpace(shippingType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isFaireCustomer reports whether customerID belongs to Faire under the profile.
+func (p ShipmentCSVProfile) isFaireCustomer(customerID string) bool {
+	if len(p.FaireCustomerIDs) == 0 {
+		return true
+	}
+	for _, id := range p.FaireCustomerIDs {
+		if id == customerID {
+			return true
+		}
+	}
+	return false
+}
+
+// isSaleSource reports whether saleSource is accepted by the profile.
+func (p ShipmentCSVProfile) isSaleSource(saleSource string) bool {
+	for _, source := range p.SaleSources {
+		if source == saleSource {
+			return true
+		}
+	}
+	return false
+}
+
+// clone returns a copy of p whose slices can be modified without changing p.
+func (p ShipmentCSVProfile) clone() ShipmentCSVProfile {
+	p.FaireCustomerIDs = append([]string(nil), p.FaireCustomerIDs...)
+	p.SaleSources = append([]string(nil), p.SaleSources...)
+	if p.Carriers != nil {
+		carriers := make(map[string]string, len(p.Carriers))
+		for name, code := range p.Carriers {
+			carriers[name] = code
+		}
+		p.Carriers = carriers
+	}
+	if p.ShippingTypes != nil {
+		shippingTypes := make(map[string]string, len(p.ShippingTypes))
+		for billingType, shippingType := range p.ShippingTypes {
+			shippingTypes[billingType] = shippingType
+		}
+		p.ShippingTypes = shippingTypes
+	}
+	return p
+}
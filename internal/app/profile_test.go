@@ -0,0 +1,117 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadShipmentCSVProfile(t *testing.T) {
+	dir := t.TempDir()
+	profilePath := filepath.Join(dir, "profile.json")
+	profileJSON := `{
+  "name": "warehouse-b",
+  "columns": {"po_numbers": "Customer PO", "tracking_code": "Tracking Number", "source_document_key": "", "billing_type": ""},
+  "faire_customer_ids": ["F1", "F2"]
+}`
+	if err := os.WriteFile(profilePath, []byte(profileJSON), 0o644); err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+
+	profile, err := LoadShipmentCSVProfile(profilePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.Name != "warehouse-b" || profile.Columns.PONumbers != "Customer PO" || profile.Columns.Carrier != "Ship Carrier Name" {
+		t.Errorf("profile = %+v, want overrides applied over the defaults", profile)
+	}
+	if len(profile.SaleSources) != len(DefaultShipmentCSVProfile.SaleSources) {
+		t.Errorf("profile sale sources = %v, want the defaults", profile.SaleSources)
+	}
+
+	csvContent := `Customer PO,Tracking Number,Shipment Charges Applied Total,Ship Carrier Name,Recipient Customer ID,Sale Source (UDF)
+ORDER1,TRACK1,1.50,UPS,F1,BSC
+ORDER2,TRACK2,2.00,UPS,0090671,BSC
+ORDER3,TRACK3,3.00,UPS,F2,SM
+`
+	csvPath := writeShipmentsCSV(t, csvContent)
+	parsed, err := ReadShipmentsCSVWithProfile(csvPath, profile)
+	if err != nil {
+		t.Fatalf("unexpected error reading CSV: %v", err)
+	}
+	if len(parsed.Shipments) != 2 || parsed.Shipments[0].PONumber != "ORDER1" || parsed.Shipments[1].TrackingCode != "TRACK3" {
+		t.Errorf("shipments = %+v, want ORDER1 and ORDER3", parsed.Shipments)
+	}
+	if len(parsed.Skipped) != 1 || parsed.Skipped[0].Kind != SkipOtherCustomer || parsed.Skipped[0].Line != 3 {
+		t.Errorf("skipped = %+v, want line 3 for another customer", parsed.Skipped)
+	}
+
+	if _, err := ReadShipmentsCSV(csvPath); err == nil || !strings.Contains(err.Error(), "missing required header") {
+		t.Errorf("ReadShipmentsCSV() error = %v, want a missing header error with the default profile", err)
+	}
+}
+
+func TestLoadShipmentCSVProfileValidation(t *testing.T) {
+	profilePath := filepath.Join(t.TempDir(), "profile.json")
+	if err := os.WriteFile(profilePath, []byte(`{"columns": {"tracking_code": ""}}`), 0o644); err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+	if _, err := LoadShipmentCSVProfile(profilePath); err == nil || !strings.Contains(err.Error(), "tracking_code") {
+		t.Errorf("LoadShipmentCSVProfile() error = %v, want a tracking_code validation error", err)
+	}
+
+	t.Setenv("FAIRE_SHIPMENT_PROFILE", "")
+	profile, err := ShipmentCSVProfileFromEnv()
+	if err != nil || profile.Name != DefaultShipmentCSVProfile.Name {
+		t.Errorf("ShipmentCSVProfileFromEnv() = %+v, %v, want the default profile", profile, err)
+	}
+}
+
+func TestShipmentProfileShippingTypes(t *testing.T) {
+	dir := t.TempDir()
+	profilePath := filepath.Join(dir, "profile.json")
+	profileJSON := `{"shipping_types": {"Third Party": "SHIP_WITH_FAIRE", "faire label": "ship_with_faire"}}`
+	if err := os.WriteFile(profilePath, []byte(profileJSON), 0o644); err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+	profile, err := LoadShipmentCSVProfile(profilePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,ORDER1,1Z999AA10123456784,1.00,UPS,Prepaid,0090671,BSC
+DOC2,ORDER2,1Z999AA10123456784,1.00,UPS,third party,0090671,BSC
+DOC3,ORDER3,1Z999AA10123456784,1.00,UPS,Faire Label,0090671,BSC
+`
+	csvPath := writeShipmentsCSV(t, csvContent)
+	parsed, err := ReadShipmentsCSVWithProfile(csvPath, profile)
+	if err != nil {
+		t.Fatalf("unexpected error reading CSV: %v", err)
+	}
+	want := []string{ShippingTypeShipOnYourOwn, ShippingTypeShipWithFaire, ShippingTypeShipWithFaire}
+	if len(parsed.Shipments) != len(want) {
+		t.Fatalf("shipments = %+v, want %d", parsed.Shipments, len(want))
+	}
+	for i, shipment := range parsed.Shipments {
+		if shipment.ShippingType != want[i] {
+			t.Errorf("shipment %d shipping type = %q, want %q", i, shipment.ShippingType, want[i])
+		}
+	}
+
+	if err := os.WriteFile(profilePath, []byte(`{"shipping_types": {"Collect": "SHIP_BY_PIGEON"}}`), 0o644); err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+	if _, err := LoadShipmentCSVProfile(profilePath); err == nil || !strings.Contains(err.Error(), "not a Faire shipping type") {
+		t.Errorf("LoadShipmentCSVProfile() error = %v, want an unknown shipping type error", err)
+	}
+
+	clashing := `{"shipping_types": {"Third Party": "SHIP_WITH_FAIRE", "third party ": "SHIP_ON_YOUR_OWN"}}`
+	if err := os.WriteFile(profilePath, []byte(clashing), 0o644); err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+	if _, err := LoadShipmentCSVProfile(profilePath); err == nil || !strings.Contains(err.Error(), "matched ignoring case") {
+		t.Errorf("LoadShipmentCSVProfile() error = %v, want a clashing billing type error", err)
+	}
+}
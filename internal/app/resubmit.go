@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// SaveShipmentPayloads writes payloads to path as JSON so they can be resubmitted later with RetryShipments.
+func SaveShipmentPayloads(path string, payloads []ShipmentPayload) error {
+	if payloads == nil {
+		payloads = []ShipmentPayload{}
+	}
+	data, err := json.MarshalIndent(payloads, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode shipments: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("save shipments: %w", err)
+	}
+	return nil
+}
+
+// LoadShipmentPayloads reads payloads saved by SaveShipmentPayloads.
+func LoadShipmentPayloads(path string) ([]ShipmentPayload, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read saved shipments: %w", err)
+	}
+	var payloads []ShipmentPayload
+	if err := json.Unmarshal(data, &payloads); err != nil {
+		return nil, fmt.Errorf("parse saved shipments %q: %w", path, err)
+	}
+	for i, payload := range payloads {
+		if payload.OrderID == "" || payload.SaleSource == "" {
+			return nil, fmt.Errorf("saved shipment %d in %q has no order ID or sale source", i+1, path)
+		}
+	}
+	return payloads, nil
+}
+
+// RetryShipments resubmits payloads from an earlier run, such as its failures, without reading the original export.
+// Payloads whose sale source has no API token are returned as failed in their place among the results, and error
+// messages from the earlier run are replaced.
+// Canceling ctx stops sending further requests, as in ProcessShipmentsWithOptions.
+func RetryShipments(ctx context.Context, payloads []ShipmentPayload, client FaireClientInterface, options ShipmentProcessOptions) ShipmentResults {
+	var results ShipmentResults
+	plan := shipmentPlan{batchByKey: make(map[shipmentBatchKey]*shipmentBatch)}
+	for _, payload := range payloads {
+		payload.ErrorMsg = ""
+		payload.FailureKind = ""
+		payload.Created = nil
+		apiToken, err := GetToken(payload.SaleSource)
+		if err != nil || apiToken == "" {
+			plan.fail(payload, fmt.Errorf("no API token is configured for sale source %q; %w", payload.SaleSource, errMissingToken), 0)
+			continue
+		}
+		plan.add(payload, apiToken, 0)
+	}
+
+	submitShipmentBatches(ctx, client, plan.batches, options)
+	plan.collect(&results)
+	return results
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestRetryShipments(t *testing.T) {
+	t.Setenv("BSC_API_TOKEN", "dummy-token")
+	t.Setenv("SMD_API_TOKEN", "")
+	path := filepath.Join(t.TempDir(), "failed.json")
+	saved := []ShipmentPayload{
+		{OrderID: "bo_order1", TrackingCode: "TRACK1", SaleSource: "BSC", ShippingType: "SHIP_ON_YOUR_OWN", ErrorMsg: "timeout"},
+		{OrderID: "bo_order1", TrackingCode: "TRACK2", SaleSource: "BSC", ShippingType: "SHIP_ON_YOUR_OWN", ErrorMsg: "timeout"},
+		{OrderID: "bo_order2", TrackingCode: "TRACK3", SaleSource: "BSC", ShippingType: "SHIP_ON_YOUR_OWN", ErrorMsg: "timeout"},
+		{OrderID: "bo_order3", TrackingCode: "TRACK4", SaleSource: "SM", ShippingType: "SHIP_ON_YOUR_OWN"},
+	}
+	if err := SaveShipmentPayloads(path, saved); err != nil {
+		t.Fatalf("SaveShipmentPayloads() error = %v", err)
+	}
+	payloads, err := LoadShipmentPayloads(path)
+	if err != nil {
+		t.Fatalf("LoadShipmentPayloads() error = %v", err)
+	}
+	if len(payloads) != len(saved) || payloads[2] != saved[2] {
+		t.Fatalf("loaded payloads = %+v, want %+v", payloads, saved)
+	}
+
+	mockClient := &MockFaireClient{FailOnCall: map[int]bool{2: true}}
+	results := RetryShipments(context.Background(), payloads, mockClient, ShipmentProcessOptions{})
+	if mockClient.CallCount != 2 {
+		t.Errorf("CallCount = %d, want one request per order with a token", mockClient.CallCount)
+	}
+	if len(results.Processed) != 2 || results.Processed[0].ErrorMsg != "" {
+		t.Errorf("processed = %+v, want order1's shipments without the old error", results.Processed)
+	}
+	if len(results.Failed) != 2 || results.Failed[0].TrackingCode != "TRACK3" || results.Failed[0].ErrorMsg == "timeout" || results.Failed[1].TrackingCode != "TRACK4" {
+		t.Fatalf("failed = %+v, want TRACK3 with a new error and TRACK4 without a token, in input order", results.Failed)
+	}
+	if kind := results.Failed[1].FailureKind; kind != FailureUnauthorized {
+		t.Errorf("missing token failure kind = %q, want %q", kind, FailureUnauthorized)
+	}
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// shipmentResultsCSVHeader lists the columns written by WriteShipmentResultsCSV.
+var shipmentResultsCSVHeader = []string{
+	"Order Display ID",
+	"Order ID",
+	"Sale Source",
+	"Carrier",
+	"Tracking Code",
+	"Cost",
+	"Outcome",
+	"Error",
+	"Faire Shipment ID",
+}
+
+// skippedOutcome is the Outcome column value for source rows that were never submitted.
+const skippedOutcome = "skipped"
+
+// createdShipmentID returns the ID of the shipment Faire created for payload, or an empty string.
+func createdShipmentID(payload ShipmentPayload) string {
+	if payload.Created == nil {
+		return ""
+	}
+	return payload.Created.ID
+}
+
+// skippedRowResult returns a results row filled with the order, sale source, carrier, tracking code, and cost that
+// skipped lists under columns. A PO cell naming several orders is reported as listed, without an order ID, and a
+// charge that cannot be parsed is reported as written.
+func skippedRowResult(skipped SkippedRow, columns ShipmentColumns) []string {
+	row := make([]string, len(shipmentResultsCSVHeader))
+	value := func(header string) string {
+		if header == "" {
+			return ""
+		}
+		return strings.TrimSpace(skipped.Values[header])
+	}
+	row[0] = value(columns.PONumbers)
+	if orders := ParseOrderIdentifiers(row[0]); len(orders) == 1 {
+		row[0] = OrderIDToDisplayID(OrderIdentifierToOrderID(orders[0]))
+		row[1] = OrderIdentifierToOrderID(orders[0])
+	}
+	row[2] = value(columns.SaleSource)
+	row[3] = value(columns.Carrier)
+	row[4] = value(columns.TrackingCode)
+	row[5] = value(columns.ShippingCharges)
+	if cents, err := ParseMoneyCents(row[5]); err == nil && row[5] != "" {
+		row[5] = FormatCents(cents)
+	}
+	return row
+}
+
+// ShipmentResultsFilename returns the default results filename for a run over inputPath, such as "shipments_results.csv".
+func ShipmentResultsFilename(inputPath string) string {
+	base := filepath.Base(inputPath)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	if base == "" || base == "." || base == string(filepath.Separator) {
+		base = "faire_shipments"
+	}
+	return base + "_results.csv"
+}
+
+// WriteShipmentResultsCSV writes one row per shipment in results, followed by the skipped rows other than those for other customers.
+// A relative filename is written to Downloads, while an absolute filename is preserved.
+func WriteShipmentResultsCSV(filename string, results ShipmentResults) error {
+	destination, err := resolveCSVPath(filename)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(destination)
+	if err != nil {
+		return fmt.Errorf("create CSV file %q: %w", destination, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(shipmentResultsCSVHeader); err != nil {
+		return fmt.Errorf("write CSV header: %w", err)
+	}
+
+	groups := []struct {
+		outcome  RunOutcome
+		payloads []ShipmentPayload
+	}{
+		{RunOutcomeProcessed, results.Processed},
+		{RunOutcomeFailed, results.Failed},
+		{RunOutcomeAlreadyPresent, results.AlreadyPresent},
+		{RunOutcomeDuplicate, results.Duplicates},
+	}
+	for _, group := range groups {
+		for _, payload := range group.payloads {
+			outcome := group.outcome
+			if outcome == RunOutcomeFailed {
+				outcome = failedOutcome(payload)
+			}
+			row := []string{
+				OrderIDToDisplayID(payload.OrderID),
+				payload.OrderID,
+				payload.SaleSource,
+				payload.Carrier,
+				payload.TrackingCode,
+				FormatCents(payload.MakerCostCents),
+				string(outcome),
+				payload.ErrorMsg,
+				createdShipmentID(payload),
+			}
+			if err := writer.Write(row); err != nil {
+				return fmt.Errorf("write CSV row: %w", err)
+			}
+		}
+	}
+	columns := results.columns
+	if columns == (ShipmentColumns{}) {
+		columns = DefaultShipmentCSVProfile.Columns
+	}
+	for _, skipped := range results.SkippedRows {
+		if skipped.Kind == SkipOtherCustomer {
+			continue
+		}
+		row := skippedRowResult(skipped, columns)
+		row[6] = skippedOutcome
+		row[7] = fmt.Sprintf("line %d: %s", skipped.Line, skipped.Reason)
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("write CSV row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("flush CSV: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteShipmentResultsCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.csv")
+	results := ShipmentResults{
+		Processed:      []ShipmentPayload{{OrderID: "bo_abc", SaleSource: "BSC", Carrier: "UPS", TrackingCode: "T1", MakerCostCents: 1999}},
+		Failed:         []ShipmentPayload{{OrderID: "bo_def", SaleSource: "SM", Carrier: "USPS", TrackingCode: "T2", MakerCostCents: 5, ErrorMsg: "boom"}},
+		AlreadyPresent: []ShipmentPayload{{OrderID: "bo_ghi", SaleSource: "BSC", Carrier: "UPS", TrackingCode: "T3"}},
+		SkippedRows: []SkippedRow{
+			{Line: 4, Kind: SkipOtherCustomer, Reason: "other customer"},
+			{Line: 7, Kind: SkipUnknownSaleSource, Reason: `unknown sale source "XYZ"`, Values: map[string]string{
+				"PO Numbers": "jkl", "Sale Source (UDF)": "XYZ", "Ship Carrier Name": "UPS",
+				"Master Tracking #": "T4", "Shipment Charges Applied Total": "$1,234.5", "Billing Type": "Prepaid",
+			}},
+			{Line: 9, Kind: SkipUnknownCarrier, Reason: `unknown carrier "Pony"`, Values: map[string]string{
+				"PO Numbers": "MNO, PQR", "Sale Source (UDF)": "BSC", "Ship Carrier Name": "Pony",
+			}},
+		},
+	}
+	if err := WriteShipmentResultsCSV(path, results); err != nil {
+		t.Fatalf("WriteShipmentResultsCSV() error = %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open results: %v", err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read results: %v", err)
+	}
+	want := [][]string{
+		shipmentResultsCSVHeader,
+		{"ABC", "bo_abc", "BSC", "UPS", "T1", "19.99", "processed", "", ""},
+		{"DEF", "bo_def", "SM", "USPS", "T2", "0.05", "failed", "boom", ""},
+		{"GHI", "bo_ghi", "BSC", "UPS", "T3", "0.00", "already_present", "", ""},
+		{"JKL", "bo_jkl", "XYZ", "UPS", "T4", "1234.50", "skipped", `line 7: unknown sale source "XYZ"`, ""},
+		{"MNO, PQR", "", "BSC", "Pony", "", "", "skipped", `line 9: unknown carrier "Pony"`, ""},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("results CSV = %q, want %q", rows, want)
+	}
+
+	if got := ShipmentResultsFilename("/tmp/daily shipments.xlsx"); got != "daily shipments_results.csv" {
+		t.Errorf("ShipmentResultsFilename() = %q, want %q", got, "daily shipments_results.csv")
+	}
+}
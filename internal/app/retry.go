@@ -0,0 +1,165 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultRequestTimeout bounds each attempt made by a client returned from NewFaireClient.
+const defaultRequestTimeout = 30 * time.Second
+
+// RetryPolicy controls how FaireClient retries rate-limited requests, server errors, and transport failures.
+// The zero value sends every request once.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per request; values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry; each later delay doubles up to MaxBackoff.
+	InitialBackoff time.Duration
+	// MaxBackoff caps every delay, including delays requested by a Retry-After header.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is the policy used by NewFaireClient.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+}
+
+// attempts returns the total number of attempts allowed by the policy.
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff returns the delay before retry number attempt, preferring a server-supplied retryAfter.
+// Computed delays use equal jitter so concurrent workers do not retry in lockstep.
+func (p RetryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	delay := retryAfter
+	if delay <= 0 {
+		delay = p.InitialBackoff
+		for i := 1; i < attempt && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+			delay *= 2
+		}
+		if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+			delay = p.MaxBackoff
+		}
+		if delay > 1 {
+			delay = delay/2 + rand.N(delay/2)
+		}
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// isRetryable reports whether err from one attempt of req is worth retrying.
+// Rate limits, server errors, and transport failures are transient; other HTTP errors and caller cancellation are permanent.
+// Requests that change data, such as posting shipments, are only repeated when Faire cannot have acted on them.
+func isRetryable(req *http.Request, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable
+	}
+	if isIdempotent(req.Method) {
+		return true
+	}
+	var unsent *unsentError
+	return errors.As(err, &unsent)
+}
+
+// isRetryableStatus reports whether Faire may succeed if a request that returned statusCode is repeated.
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isRetryableResponse reports whether a method request that returned statusCode can be repeated as is.
+// Other server errors may arrive after Faire has already applied a change, so only rate limits and 503 responses,
+// which are returned before the request is handled, are safe to repeat for a request that changes data.
+func isRetryableResponse(method string, statusCode int) bool {
+	if isIdempotent(method) {
+		return isRetryableStatus(statusCode)
+	}
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// isIdempotent reports whether repeating a method request cannot change Faire's data a second time.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// mayHaveBeenApplied reports whether err, from a request that changes data, leaves open whether Faire applied it:
+// a server error other than 503, or a transport failure after the request was sent, such as a timeout.
+func mayHaveBeenApplied(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= http.StatusInternalServerError && apiErr.StatusCode != http.StatusServiceUnavailable
+	}
+	var unsent *unsentError
+	if errors.As(err, &unsent) {
+		return false
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// unsentError wraps a transport failure that happened before a connection to Faire was made, so the request
+// cannot have reached Faire and is safe to repeat whatever its method.
+type unsentError struct {
+	err error
+}
+
+// Error returns the transport failure's message.
+func (e *unsentError) Error() string { return e.err.Error() }
+
+// Unwrap returns the transport failure.
+func (e *unsentError) Unwrap() error { return e.err }
+
+// parseRetryAfter returns the delay requested by a Retry-After header in seconds or HTTP-date form.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// sleepContext waits for delay or until ctx is done, returning ctx's error in the latter case.
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
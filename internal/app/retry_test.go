@@ -0,0 +1,162 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestFaireClientRetriesTransientFailures confirms 429 and 503 responses are retried with the Retry-After delay.
+func TestFaireClientRetriesTransientFailures(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch attempts.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	client := &FaireClient{
+		BaseURL: server.URL,
+		Retry:   RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 10 * time.Second},
+		sleep: func(ctx context.Context, delay time.Duration) error {
+			delays = append(delays, delay)
+			return nil
+		},
+	}
+	if _, err := client.AddShipment(context.Background(), ShipmentPayload{OrderID: "bo_abc"}, "token"); err != nil {
+		t.Fatalf("AddShipment returned an error: %v", err)
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Fatalf("attempts = %d, want 3", got)
+	}
+	if len(delays) != 2 || delays[0] != 7*time.Second {
+		t.Fatalf("delays = %v, want Retry-After delay of 7s followed by a backoff delay", delays)
+	}
+	if delays[1] < time.Second || delays[1] > 2*time.Second {
+		t.Errorf("second delay = %v, want jittered backoff between 1s and 2s", delays[1])
+	}
+}
+
+// TestFaireClientDoesNotRetryPermanentFailures confirms client errors fail on the first attempt.
+func TestFaireClientDoesNotRetryPermanentFailures(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		http.Error(w, "invalid carrier", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := &FaireClient{
+		BaseURL: server.URL,
+		Retry:   DefaultRetryPolicy,
+		sleep:   func(ctx context.Context, delay time.Duration) error { return nil },
+	}
+	_, err := client.AddShipment(context.Background(), ShipmentPayload{OrderID: "bo_abc"}, "token")
+	if err == nil || !strings.Contains(err.Error(), "invalid carrier") {
+		t.Fatalf("AddShipment error = %v, want invalid carrier error", err)
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+}
+
+// TestFaireClientDoesNotDuplicateCommittedShipments confirms a shipment post that Faire commits before answering 502
+// is checked against the order rather than resent, so only one shipment is created.
+func TestFaireClientDoesNotDuplicateCommittedShipments(t *testing.T) {
+	var mu sync.Mutex
+	var shipments []OrderShipment
+	var posts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPost:
+			posts++
+			var request ShipmentRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			for _, payload := range request.Shipments {
+				shipments = append(shipments, OrderShipment{ID: "sh_" + payload.TrackingCode, TrackingCode: payload.TrackingCode})
+			}
+			if posts == 1 {
+				http.Error(w, "bad gateway", http.StatusBadGateway)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(shipments)
+		default:
+			_ = json.NewEncoder(w).Encode(Order{ID: "bo_abc", Shipments: shipments})
+		}
+	}))
+	defer server.Close()
+
+	client := &FaireClient{
+		BaseURL: server.URL,
+		Retry:   DefaultRetryPolicy,
+		sleep:   func(ctx context.Context, delay time.Duration) error { return nil },
+	}
+	created, err := client.AddShipment(context.Background(), ShipmentPayload{OrderID: "bo_abc", TrackingCode: "1Z999"}, "token")
+	if err != nil {
+		t.Fatalf("AddShipment returned an error: %v", err)
+	}
+	if len(shipments) != 1 || posts != 1 {
+		t.Fatalf("Faire has %d shipments after %d posts, want 1 shipment from 1 post", len(shipments), posts)
+	}
+	if len(created) != 1 || created[0].TrackingCode != "1Z999" {
+		t.Errorf("created = %+v, want the shipment found on the order", created)
+	}
+}
+
+// TestFaireClientTimeout confirms Timeout bounds a request that never answers.
+func TestFaireClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := &FaireClient{BaseURL: server.URL, Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	if _, err := client.GetOrderByID(context.Background(), "ABC", "token"); err == nil {
+		t.Fatal("GetOrderByID returned no error for a hung request")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("request took %v, want it bounded by the timeout", elapsed)
+	}
+}
+
+// TestParseRetryAfter covers both Retry-After header forms.
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 7, 16, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"5":                             5 * time.Second,
+		"-1":                            0,
+		"Wed, 16 Jul 2025 12:00:30 GMT": 30 * time.Second,
+		"not a date":                    0,
+	}
+	for header, want := range tests {
+		if got := parseRetryAfter(header, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"strconv"
+	"path/filepath"
 	"strings"
 )
 
@@ -13,16 +13,64 @@ type Shipment struct {
 	CustomerNumber string
 	PONumber       string
 	BillingType    string
-	Carrier        string
+	ShippingType   string // Faire shipping type derived from BillingType by the profile.
+	Carrier        string // Faire carrier code normalized from the shipping system's carrier name.
 	TrackingCode   string
 	MakerCostCents int
 	SaleSource     string
+	Line           int // One-based line in the source file, used when reporting problems with the row.
 }
 
+// SkipKind classifies why a shipping-system row was not submitted to Faire.
+type SkipKind string
+
+const (
+	// SkipOtherCustomer marks a shipment to a customer other than Faire; these rows are expected in every export.
+	SkipOtherCustomer SkipKind = "other_customer"
+	// SkipUnknownSaleSource marks a Faire shipment whose sale source is not recognized.
+	SkipUnknownSaleSource SkipKind = "unknown_sale_source"
+	// SkipMissingToken marks a Faire shipment whose sale source has no configured API token.
+	SkipMissingToken SkipKind = "missing_token"
+	// SkipUnknownCarrier marks a Faire shipment whose carrier name does not map to a Faire carrier code.
+	SkipUnknownCarrier SkipKind = "unknown_carrier"
+	// SkipInvalidTrackingCode marks a Faire shipment whose tracking code is empty or was mangled by a spreadsheet.
+	SkipInvalidTrackingCode SkipKind = "invalid_tracking_code"
+)
+
+// SkippedRow records a source row that did not become a submitted shipment.
+type SkippedRow struct {
+	Line   int               `json:"line"`
+	Kind   SkipKind          `json:"kind"`
+	Reason string            `json:"reason"`
+	Values map[string]string `json:"values"` // Cell values keyed by header; rows skipped after parsing hold only the parsed fields.
+}
+
+// ParsedShipments holds the shipments read from a shipping-system export and the rows that were skipped.
+type ParsedShipments struct {
+	Shipments []Shipment
+	Skipped   []SkippedRow
+	// Warnings flags parsed shipments with suspicious data, such as a tracking code with a bad check digit.
+	Warnings []ShipmentWarning
+}
+
+// ParseShipmentsCSV returns the Faire shipments in the CSV at path, ignoring skipped rows.
 func ParseShipmentsCSV(path string) ([]Shipment, error) {
+	parsed, err := ReadShipmentsCSV(path)
+	return parsed.Shipments, err
+}
+
+// ReadShipmentsCSV reads the CSV at path with DefaultShipmentCSVProfile.
+func ReadShipmentsCSV(path string) (ParsedShipments, error) {
+	return ReadShipmentsCSVWithProfile(path, DefaultShipmentCSVProfile)
+}
+
+// ReadShipmentsCSVWithProfile returns the Faire shipments in the CSV at path along with every row that was skipped and why.
+// profile selects the columns and the rows that count as Faire shipments.
+func ReadShipmentsCSVWithProfile(path string, profile ShipmentCSVProfile) (ParsedShipments, error) {
+	var parsed ParsedShipments
 	file, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return parsed, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
@@ -30,9 +78,128 @@ func ParseShipmentsCSV(path string) ([]Shipment, error) {
 
 	headers, err := r.Read()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read headers: %w", err)
+		return parsed, fmt.Errorf("failed to read headers: %w", err)
+	}
+	rows, err := newShipmentRowParser(headers, profile)
+	if err != nil {
+		return parsed, err
+	}
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return parsed, fmt.Errorf("failed to read record: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+		if err := rows.add(&parsed, record, line); err != nil {
+			return parsed, err
+		}
 	}
+	rows.finish(&parsed)
+	return parsed, nil
+}
+
+// ReadShipmentsFile reads a shipping-system export with profile, choosing the format from the file extension.
+// Files ending in .xlsx are read as Excel workbooks and everything else as CSV.
+func ReadShipmentsFile(path string, profile ShipmentCSVProfile) (ParsedShipments, error) {
+	if strings.EqualFold(filepath.Ext(path), ".xlsx") {
+		return ReadShipmentsXLSX(path, profile)
+	}
+	return ReadShipmentsCSVWithProfile(path, profile)
+}
+
+// ReadShipmentsXLSX returns the Faire shipments in the Excel workbook at path along with every row that was skipped and why.
+// It reads profile.Sheet, or the first visible sheet containing the profile's headers, and starts after the first
+// row that contains all of them so title rows above the table are ignored.
+func ReadShipmentsXLSX(path string, profile ShipmentCSVProfile) (ParsedShipments, error) {
+	var parsed ParsedShipments
+	wb, err := openXLSX(path)
+	if err != nil {
+		return parsed, err
+	}
+	defer wb.Close()
+
+	sheets := wb.sheets
+	if profile.Sheet != "" {
+		sheet, ok := wb.sheet(profile.Sheet)
+		if !ok {
+			return parsed, fmt.Errorf("workbook has no sheet named %q", profile.Sheet)
+		}
+		sheets = []xlsxSheet{sheet}
+	}
+
+	required := profile.requiredHeaders()
+	for _, sheet := range sheets {
+		if sheet.hidden && profile.Sheet == "" {
+			continue
+		}
+		rows, err := wb.rows(sheet)
+		if err != nil {
+			return parsed, err
+		}
+		header := findHeaderRow(rows, required)
+		if header < 0 {
+			continue
+		}
+		parser, err := newShipmentRowParser(rows[header].cells, profile)
+		if err != nil {
+			return parsed, err
+		}
+		for _, row := range rows[header+1:] {
+			if err := parser.add(&parsed, row.cells, row.line); err != nil {
+				return parsed, err
+			}
+		}
+		parser.finish(&parsed)
+		return parsed, nil
+	}
+	return parsed, fmt.Errorf("missing required headers: no sheet contains %s", strings.Join(required, ", "))
+}
+
+// findHeaderRow returns the index of the first row containing every required header, or -1.
+func findHeaderRow(rows []xlsxRow, required []string) int {
+	for i, row := range rows {
+		present := make(map[string]bool, len(row.cells))
+		for _, cell := range row.cells {
+			present[strings.TrimSpace(cell)] = true
+		}
+		found := true
+		for _, header := range required {
+			if !present[header] {
+				found = false
+				break
+			}
+		}
+		if found {
+			return i
+		}
+	}
+	return -1
+}
+
+// isBlankRecord reports whether every cell in record is empty or whitespace.
+func isBlankRecord(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
 
+// shipmentRowParser turns export rows into shipments using a profile's column mapping and filters.
+type shipmentRowParser struct {
+	profile  ShipmentCSVProfile
+	carriers CarrierMapper
+	headers  []string
+	idx      map[string]int
+}
+
+// newShipmentRowParser indexes headers and rejects exports missing a column required by profile.
+func newShipmentRowParser(headers []string, profile ShipmentCSVProfile) (*shipmentRowParser, error) {
 	var idx = make(map[string]int)
 	for i, h := range headers {
 		// Clean up header names from potential leading/trailing spaces if any
@@ -40,62 +207,171 @@ func ParseShipmentsCSV(path string) ([]Shipment, error) {
 	}
 
 	// Validate required headers exist
-	requiredHeaders := []string{"Source Document Key", "PO Numbers", "Master Tracking #", "Shipment Charges Applied Total", "Ship Carrier Name", "Billing Type", "Recipient Customer ID", "Sale Source (UDF)"}
-	for _, rh := range requiredHeaders {
+	for _, rh := range profile.requiredHeaders() {
 		if _, ok := idx[rh]; !ok {
 			return nil, fmt.Errorf("missing required header: %s", rh)
 		}
 	}
+	return &shipmentRowParser{profile: profile, carriers: NewCarrierMapper(profile.Carriers), headers: headers, idx: idx}, nil
+}
 
-	validSaleSources := map[string]struct{}{
-		"21":  {},
-		"ASC": {},
-		"BJP": {},
-		"BSC": {},
-		"GTG": {},
-		"OAT": {},
-		"SM":  {},
+// value returns the cell for header in record, or an empty string when the profile does not map the column.
+func (p *shipmentRowParser) value(record []string, header string) string {
+	i, ok := p.idx[header]
+	if header == "" || !ok || i >= len(record) {
+		return ""
 	}
+	return record[i]
+}
 
-	var shipments []Shipment
-	for {
-		record, err := r.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to read record: %w", err)
-		}
+// add appends the shipment or skipped row built from record at the one-based line to parsed.
+func (p *shipmentRowParser) add(parsed *ParsedShipments, record []string, line int) error {
+	columns := p.profile.Columns
 
-		// Clean and retrieve Recipient Customer ID
-		recipientCustomerID := record[idx["Recipient Customer ID"]]
+	// Clean and retrieve Recipient Customer ID
+	recipientCustomerID := p.value(record, columns.CustomerID)
 
-		saleSource := record[idx["Sale Source (UDF)"]]
-		if recipientCustomerID != "0090671" {
-			continue
-		}
-		if _, ok := validSaleSources[saleSource]; !ok {
-			continue
-		}
+	saleSource := p.value(record, columns.SaleSource)
+	poNumber := p.value(record, columns.PONumbers)
+	if !p.profile.isFaireCustomer(recipientCustomerID) {
+		parsed.Skipped = append(parsed.Skipped, SkippedRow{
+			Line:   line,
+			Kind:   SkipOtherCustomer,
+			Reason: fmt.Sprintf("recipient customer ID %q is not the Faire customer", recipientCustomerID),
+			Values: rowValues(p.headers, record),
+		})
+		return nil
+	}
+	if !p.profile.isSaleSource(saleSource) {
+		parsed.Skipped = append(parsed.Skipped, SkippedRow{
+			Line:   line,
+			Kind:   SkipUnknownSaleSource,
+			Reason: fmt.Sprintf("unknown sale source %q for PO %q", saleSource, poNumber),
+			Values: rowValues(p.headers, record),
+		})
+		return nil
+	}
 
-		// Parse Shipment Charges Applied Total
-		makerCostStr := record[idx["Shipment Charges Applied Total"]]
-		makerCostDollars, err := strconv.ParseFloat(makerCostStr, 64)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse MakerCostCents '%s': %w", makerCostStr, err)
-		}
-		// Convert dollars to cents (multiply by 100) and cast to int
-		makerCostCents := int(makerCostDollars * 100)
+	carrierName := p.value(record, columns.Carrier)
+	carrier, ok := p.carriers.Normalize(carrierName)
+	if !ok {
+		parsed.Skipped = append(parsed.Skipped, SkippedRow{
+			Line:   line,
+			Kind:   SkipUnknownCarrier,
+			Reason: fmt.Sprintf("unknown carrier %q for PO %q; add it to the profile's carriers", carrierName, poNumber),
+			Values: rowValues(p.headers, record),
+		})
+		return nil
+	}
+
+	trackingCode := p.value(record, columns.TrackingCode)
+	warning, err := checkTrackingCode(carrier, trackingCode)
+	if err != nil {
+		parsed.Skipped = append(parsed.Skipped, SkippedRow{
+			Line:   line,
+			Kind:   SkipInvalidTrackingCode,
+			Reason: fmt.Sprintf("%v (PO %q)", err, poNumber),
+			Values: rowValues(p.headers, record),
+		})
+		return nil
+	}
+	if warning != "" {
+		parsed.Warnings = append(parsed.Warnings, ShipmentWarning{Line: line, TrackingCode: trackingCode, Message: fmt.Sprintf("%s (PO %q)", warning, poNumber)})
+	}
+
+	// Parse Shipment Charges Applied Total
+	makerCostStr := p.value(record, columns.ShippingCharges)
+	makerCostCents, err := ParseMoneyCents(makerCostStr)
+	if err != nil {
+		return fmt.Errorf("line %d: failed to parse MakerCostCents '%s' in column %q: %w", line, makerCostStr, columns.ShippingCharges, err)
+	}
 
-		shipments = append(shipments, Shipment{
+	// A box holding items from several orders lists every PO in one cell; each order gets its own shipment.
+	orders := ParseOrderIdentifiers(poNumber)
+	if len(orders) == 0 {
+		orders = []string{poNumber}
+	}
+	charges := p.profile.POCharges.allocate(makerCostCents, len(orders))
+
+	billingType := p.value(record, columns.BillingType)
+	for i, order := range orders {
+		if charges != nil {
+			makerCostCents = charges[i]
+		}
+		parsed.Shipments = append(parsed.Shipments, Shipment{
 			CustomerNumber: recipientCustomerID,
-			PONumber:       record[idx["PO Numbers"]],
-			BillingType:    record[idx["Billing Type"]],
-			Carrier:        record[idx["Ship Carrier Name"]],
-			TrackingCode:   record[idx["Master Tracking #"]],
+			PONumber:       order,
+			BillingType:    billingType,
+			ShippingType:   p.profile.shippingType(billingType),
+			Carrier:        carrier,
+			TrackingCode:   trackingCode,
 			MakerCostCents: makerCostCents,
 			SaleSource:     saleSource,
+			Line:           line,
 		})
 	}
-	return shipments, nil
+	return nil
+}
+
+// finish applies adjustments that span rows, such as dividing an order's charge among its packages, once every row is added.
+func (p *shipmentRowParser) finish(parsed *ParsedShipments) {
+	allocatePackageCharges(parsed.Shipments, p.profile.PackageCharges, p.profile.PackageChargesRepeated)
+}
+
+// values returns the shipment's fields keyed by the headers in columns, for reporting a shipment that was skipped after parsing.
+func (s Shipment) values(columns ShipmentColumns) map[string]string {
+	values := map[string]string{
+		columns.PONumbers:       s.PONumber,
+		columns.SaleSource:      s.SaleSource,
+		columns.Carrier:         s.Carrier,
+		columns.TrackingCode:    s.TrackingCode,
+		columns.ShippingCharges: FormatCents(s.MakerCostCents),
+		columns.BillingType:     s.BillingType,
+		columns.CustomerID:      s.CustomerNumber,
+	}
+	delete(values, "")
+	return values
+}
+
+// rowValues returns record keyed by the trimmed headers for reporting skipped rows.
+func rowValues(headers, record []string) map[string]string {
+	values := make(map[string]string, len(headers))
+	for i, header := range headers {
+		if i < len(record) {
+			values[strings.TrimSpace(header)] = record[i]
+		}
+	}
+	return values
+}
+
+// CountUnexpectedSkips returns the number of rows skipped for a reason other than belonging to another customer.
+// These rows usually indicate a data problem that would otherwise lose a shipment.
+func CountUnexpectedSkips(rows []SkippedRow) int {
+	count := 0
+	for _, row := range rows {
+		if row.Kind != SkipOtherCustomer {
+			count++
+		}
+	}
+	return count
+}
+
+// FormatSkippedRows returns one line per skipped row, summarizing rows for other customers in a single count.
+func FormatSkippedRows(rows []SkippedRow) string {
+	if len(rows) == 0 {
+		return "  None\n"
+	}
+	var b strings.Builder
+	otherCustomers := 0
+	for _, row := range rows {
+		if row.Kind == SkipOtherCustomer {
+			otherCustomers++
+			continue
+		}
+		fmt.Fprintf(&b, "  Line %d: %s\n", row.Line, row.Reason)
+	}
+	if otherCustomers > 0 {
+		fmt.Fprintf(&b, "  %d rows for customers other than Faire were ignored\n", otherCustomers)
+	}
+	return b.String()
 }
@@ -3,10 +3,21 @@ package app
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
 
+// writeShipmentsCSV writes content to a shipments.csv in a temporary directory removed after the test, and returns its path.
+func writeShipmentsCSV(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "shipments.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+	return path
+}
+
 func TestParseShipmentsCSV(t *testing.T) {
 	// Create a temporary CSV file with the expected headers and data, including Sale Source (UDF)
 	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
@@ -18,21 +29,9 @@ DOC5,ORDER127,TRACK127,7.00,UPS,Consignee,0090671,BSC
 DOC6,ORDER128,TRACK128,8.00,UPS,Consignee,0090671,XYZ
 DOC7,ORDER129,TRACK129,9.00,UPS,Consignee,0090671,SM
 `
-	f, err := os.CreateTemp("", "shipments-*.csv")
-	if err != nil {
-		t.Fatalf("failed to create temp file: %v", err)
-	}
-	defer os.Remove(f.Name()) // Clean up the temporary file
-	defer f.Close()           // Close the file
-
-	if _, err := f.WriteString(csvContent); err != nil {
-		t.Fatalf("failed to write to temp file: %v", err)
-	}
+	path := writeShipmentsCSV(t, csvContent)
 
-	// Close the file so ParseShipmentsCSV can open and read it
-	f.Close()
-
-	shipments, err := ParseShipmentsCSV(f.Name())
+	shipments, err := ParseShipmentsCSV(path)
 	if err != nil {
 		t.Fatalf("unexpected error parsing CSV: %v", err)
 	}
@@ -77,19 +76,9 @@ DOC7,ORDER129,TRACK129,9.00,UPS,Consignee,0090671,SM
 	missingHeaderCSV := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Recipient Customer ID,Sale Source (UDF)
 DOC1,ORDER123,TRACK123,10.00,UPS,0090671,SM
 `
-	fMissing, err := os.CreateTemp("", "missing-header-*.csv")
-	if err != nil {
-		t.Fatalf("failed to create temp file for missing header test: %v", err)
-	}
-	defer os.Remove(fMissing.Name())
-	defer fMissing.Close()
+	missingPath := writeShipmentsCSV(t, missingHeaderCSV)
 
-	if _, err := fMissing.WriteString(missingHeaderCSV); err != nil {
-		t.Fatalf("failed to write to temp file for missing header test: %v", err)
-	}
-	fMissing.Close()
-
-	_, err = ParseShipmentsCSV(fMissing.Name())
+	_, err = ParseShipmentsCSV(missingPath)
 	if err == nil {
 		t.Error("expected error for missing 'Billing Type' header, got nil")
 	} else if !strings.Contains(err.Error(), "missing required header: Billing Type") {
@@ -100,22 +89,92 @@ DOC1,ORDER123,TRACK123,10.00,UPS,0090671,SM
 	invalidCostCSV := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
 DOC1,ORDER123,TRACK123,ABC,UPS,Consignee,0090671,SM
 `
-	fInvalidCost, err := os.CreateTemp("", "invalid-cost-*.csv")
-	if err != nil {
-		t.Fatalf("failed to create temp file for invalid cost test: %v", err)
-	}
-	defer os.Remove(fInvalidCost.Name())
-	defer fInvalidCost.Close()
-
-	if _, err := fInvalidCost.WriteString(invalidCostCSV); err != nil {
-		t.Fatalf("failed to write to temp file for invalid cost test: %v", err)
-	}
-	fInvalidCost.Close()
+	invalidCostPath := writeShipmentsCSV(t, invalidCostCSV)
 
-	_, err = ParseShipmentsCSV(fInvalidCost.Name())
+	_, err = ParseShipmentsCSV(invalidCostPath)
 	if err == nil {
 		t.Error("expected error for invalid MakerCostCents, got nil")
 	} else if !strings.Contains(err.Error(), "failed to parse MakerCostCents 'ABC'") {
 		t.Errorf("expected 'failed to parse MakerCostCents 'ABC'' error, got: %v", err)
+	} else if !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("expected the error to name line 2, got: %v", err)
+	}
+}
+
+func TestReadShipmentsCSVReportsSkippedRows(t *testing.T) {
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,ORDER123,TRACK123,10.00,UPS,Consignee,0090671,SM
+DOC2,ORDER124,TRACK124,20.50,FedEx,Prepaid,0000000,BSC
+DOC3,ORDER125,TRACK125,30.00,DHL,Third Party,0090671,BCS
+`
+	path := writeShipmentsCSV(t, csvContent)
+
+	parsed, err := ReadShipmentsCSV(path)
+	if err != nil {
+		t.Fatalf("unexpected error parsing CSV: %v", err)
+	}
+	if len(parsed.Shipments) != 1 || parsed.Shipments[0].Line != 2 {
+		t.Fatalf("expected one shipment from line 2, got %+v", parsed.Shipments)
+	}
+	if len(parsed.Skipped) != 2 {
+		t.Fatalf("expected 2 skipped rows, got %+v", parsed.Skipped)
+	}
+
+	other, typo := parsed.Skipped[0], parsed.Skipped[1]
+	if other.Line != 3 || other.Kind != SkipOtherCustomer {
+		t.Errorf("expected line 3 skipped for another customer, got %+v", other)
+	}
+	if typo.Line != 4 || typo.Kind != SkipUnknownSaleSource || typo.Values["Sale Source (UDF)"] != "BCS" {
+		t.Errorf("expected line 4 skipped for unknown sale source BCS, got %+v", typo)
+	}
+	if !strings.Contains(typo.Reason, `"BCS"`) {
+		t.Errorf("expected the reason to name the sale source, got %q", typo.Reason)
+	}
+	if got := CountUnexpectedSkips(parsed.Skipped); got != 1 {
+		t.Errorf("CountUnexpectedSkips() = %d, want 1", got)
+	}
+
+	formatted := FormatSkippedRows(parsed.Skipped)
+	if !strings.Contains(formatted, "Line 4: unknown sale source") || !strings.Contains(formatted, "1 rows for customers other than Faire") {
+		t.Errorf("FormatSkippedRows() = %q", formatted)
+	}
+}
+
+func TestReadShipmentsCSVSplitsMultiplePONumbers(t *testing.T) {
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,"ORDER1, ORDER2 ORDER3",1Z999AA10123456784,10.00,UPS,Consignee,0090671,SM
+DOC2,ORDER4,1Z999AA10123456784,4.00,UPS,Consignee,0090671,SM
+`
+	path := writeShipmentsCSV(t, csvContent)
+
+	parsed, err := ReadShipmentsCSV(path)
+	if err != nil {
+		t.Fatalf("unexpected error parsing CSV: %v", err)
+	}
+	want := []struct {
+		po    string
+		cents int
+	}{{"ORDER1", 334}, {"ORDER2", 333}, {"ORDER3", 333}, {"ORDER4", 400}}
+	if len(parsed.Shipments) != len(want) {
+		t.Fatalf("expected %d shipments, got %+v", len(want), parsed.Shipments)
+	}
+	for i, w := range want {
+		got := parsed.Shipments[i]
+		if got.PONumber != w.po || got.MakerCostCents != w.cents || got.TrackingCode != "1Z999AA10123456784" {
+			t.Errorf("shipment %d = %+v, want PO %s charged %d cents", i, got, w.po, w.cents)
+		}
+	}
+	if parsed.Shipments[2].Line != 2 {
+		t.Errorf("expected split shipments to keep line 2, got %d", parsed.Shipments[2].Line)
+	}
+
+	profile := DefaultShipmentCSVProfile.clone()
+	profile.POCharges = ChargesAsListed
+	parsed, err = ReadShipmentsCSVWithProfile(path, profile)
+	if err != nil {
+		t.Fatalf("unexpected error parsing CSV: %v", err)
+	}
+	if len(parsed.Shipments) != 4 || parsed.Shipments[1].MakerCostCents != 1000 {
+		t.Errorf("expected every order charged the full amount as listed, got %+v", parsed.Shipments)
 	}
 }
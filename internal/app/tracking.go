@@ -0,0 +1,123 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ShipmentWarning flags a shipment that will still be submitted but looks suspicious, such as a tracking code with a bad check digit.
+type ShipmentWarning struct {
+	Line         int    `json:"line"`
+	TrackingCode string `json:"tracking_code"`
+	Message      string `json:"message"`
+}
+
+var (
+	// scientificNotation matches numbers that a spreadsheet has rewritten in exponent form, losing digits.
+	scientificNotation = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?E[+-]?[0-9]+$`)
+	upsTrackingCode    = regexp.MustCompile(`^1Z[0-9A-Z]{16}$`)
+	usps20Or22Digits   = regexp.MustCompile(`^[0-9]{20}$|^[0-9]{22}$`)
+	uspsInternational  = regexp.MustCompile(`^[A-Z]{2}[0-9]{9}US$`)
+	fedexTrackingCode  = regexp.MustCompile(`^[0-9]{12}$|^[0-9]{15}$|^[0-9]{20}$|^[0-9]{22}$`)
+	dhlExpressWaybill  = regexp.MustCompile(`^[0-9]{10}$`)
+	dhlTrackingCode    = regexp.MustCompile(`^[0-9]{10,11}$|^JJD[0-9]{10,}$|^JVGL[0-9]{10,}$|^[0-9A-Z]{13,39}$`)
+)
+
+// checkTrackingCode validates code for the Faire carrier code carrier.
+// It returns an error for codes that cannot be right, such as empty or spreadsheet-mangled codes, and a warning
+// for codes that do not match the carrier's usual format or check digit. Carriers without known formats are not checked.
+func checkTrackingCode(carrier, code string) (warning string, err error) {
+	compact := strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code)))
+	switch {
+	case compact == "":
+		return "", fmt.Errorf("tracking code is empty")
+	case scientificNotation.MatchString(compact):
+		return "", fmt.Errorf("tracking code %q is in scientific notation; format the column as text in the spreadsheet", code)
+	}
+
+	switch carrier {
+	case "UPS":
+		if !upsTrackingCode.MatchString(compact) {
+			return fmt.Sprintf("tracking code %q does not look like a UPS 1Z tracking number", code), nil
+		}
+		if !validUPSCheckDigit(compact) {
+			return fmt.Sprintf("UPS tracking code %q has an invalid check digit", code), nil
+		}
+	case "USPS":
+		switch {
+		case usps20Or22Digits.MatchString(compact):
+			if !validMod10CheckDigit(compact) {
+				return fmt.Sprintf("USPS tracking code %q has an invalid check digit", code), nil
+			}
+		case uspsInternational.MatchString(compact):
+		default:
+			return fmt.Sprintf("tracking code %q does not look like a USPS tracking number", code), nil
+		}
+	case "FEDEX":
+		if !fedexTrackingCode.MatchString(compact) {
+			return fmt.Sprintf("tracking code %q does not look like a FedEx tracking number", code), nil
+		}
+	case "DHL":
+		if dhlExpressWaybill.MatchString(compact) && !validDHLExpressCheckDigit(compact) {
+			return fmt.Sprintf("DHL Express waybill %q has an invalid check digit", code), nil
+		}
+		if !dhlTrackingCode.MatchString(compact) {
+			return fmt.Sprintf("tracking code %q does not look like a DHL tracking number", code), nil
+		}
+	}
+	return "", nil
+}
+
+// validUPSCheckDigit verifies the final character of an 18-character UPS 1Z tracking number.
+func validUPSCheckDigit(code string) bool {
+	sum := 0
+	for i, r := range code[2:17] {
+		n := int(r - '0')
+		if r >= 'A' && r <= 'Z' {
+			n = (int(r) - 63) % 10
+		}
+		if i%2 == 1 {
+			n *= 2
+		}
+		sum += n
+	}
+	return int(code[17]-'0') == (10-sum%10)%10
+}
+
+// validMod10CheckDigit verifies the final digit of a USPS barcode number using the 3-1 weighted mod 10 check.
+func validMod10CheckDigit(code string) bool {
+	sum := 0
+	weight := 3
+	for i := len(code) - 2; i >= 0; i-- {
+		sum += int(code[i]-'0') * weight
+		weight = 4 - weight
+	}
+	return int(code[len(code)-1]-'0') == (10-sum%10)%10
+}
+
+// validDHLExpressCheckDigit verifies a 10-digit DHL Express waybill, whose last digit is the first nine modulo 7.
+func validDHLExpressCheckDigit(code string) bool {
+	n := 0
+	for _, r := range code[:9] {
+		n = n*10 + int(r-'0')
+	}
+	return int(code[9]-'0') == n%7
+}
+
+// FormatShipmentWarnings returns one line per warning for terminal output and result dialogs.
+func FormatShipmentWarnings(warnings []ShipmentWarning) string {
+	if len(warnings) == 0 {
+		return "  None\n"
+	}
+	var b strings.Builder
+	for _, warning := range warnings {
+		if warning.Line == 0 {
+			// Warnings about resubmitted shipments have no source line.
+			fmt.Fprintf(&b, "  %s\n", warning.Message)
+			continue
+		}
+		fmt.Fprintf(&b, "  Line %d: %s\n", warning.Line, warning.Message)
+	}
+	return b.String()
+}
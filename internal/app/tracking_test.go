@@ -0,0 +1,71 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckTrackingCode(t *testing.T) {
+	tests := []struct {
+		carrier string
+		code    string
+		warning string
+		err     string
+	}{
+		{carrier: "UPS", code: "1Z999AA10123456784"},
+		{carrier: "UPS", code: "1z 999 aa1 0123 4567 84"},
+		{carrier: "UPS", code: "1Z999AA10123456785", warning: "invalid check digit"},
+		{carrier: "UPS", code: "999AA10123456784", warning: "does not look like a UPS"},
+		{carrier: "USPS", code: "9400111899223397658533"},
+		{carrier: "USPS", code: "9400111899223397658534", warning: "invalid check digit"},
+		{carrier: "USPS", code: "EA123456789US"},
+		{carrier: "USPS", code: "12345", warning: "does not look like a USPS"},
+		{carrier: "FEDEX", code: "123456789012"},
+		{carrier: "FEDEX", code: "12345", warning: "does not look like a FedEx"},
+		{carrier: "DHL", code: "1234567891"},
+		{carrier: "DHL", code: "1234567890", warning: "invalid check digit"},
+		{carrier: "DHL", code: "JJD0099999999999"},
+		{carrier: "ONTRAC", code: "anything"},
+		{carrier: "UPS", code: "  ", err: "empty"},
+		{carrier: "USPS", code: "9.40011E+21", err: "scientific notation"},
+		{carrier: "ONTRAC", code: "1.23457e+21", err: "scientific notation"},
+	}
+	for _, tt := range tests {
+		warning, err := checkTrackingCode(tt.carrier, tt.code)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("checkTrackingCode(%q, %q) error = %v, want %q", tt.carrier, tt.code, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("checkTrackingCode(%q, %q) unexpected error = %v", tt.carrier, tt.code, err)
+		}
+		if (tt.warning == "") != (warning == "") || !strings.Contains(warning, tt.warning) {
+			t.Errorf("checkTrackingCode(%q, %q) warning = %q, want %q", tt.carrier, tt.code, warning, tt.warning)
+		}
+	}
+}
+
+func TestReadShipmentsValidatesTrackingCodes(t *testing.T) {
+	csvContent := `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,ORDER1,1Z999AA10123456784,1.00,UPS,Prepaid,0090671,BSC
+DOC2,ORDER2,1.23457E+21,1.00,USPS,Prepaid,0090671,BSC
+DOC3,ORDER3,1Z999AA10123456785,1.00,UPS,Prepaid,0090671,BSC
+DOC4,ORDER4,,1.00,UPS,Prepaid,0090671,BSC
+`
+	path := writeShipmentsCSV(t, csvContent)
+	parsed, err := ReadShipmentsCSV(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parsed.Shipments) != 2 {
+		t.Errorf("shipments = %+v, want ORDER1 and ORDER3", parsed.Shipments)
+	}
+	if len(parsed.Skipped) != 2 || parsed.Skipped[0].Line != 3 || parsed.Skipped[1].Line != 5 || parsed.Skipped[0].Kind != SkipInvalidTrackingCode {
+		t.Errorf("skipped = %+v, want lines 3 and 5 with invalid tracking codes", parsed.Skipped)
+	}
+	if len(parsed.Warnings) != 1 || parsed.Warnings[0].Line != 4 {
+		t.Errorf("warnings = %+v, want line 4", parsed.Warnings)
+	}
+}
@@ -0,0 +1,272 @@
+package app
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// xlsxWorkbook is an open XLSX file with its sheet list and shared strings loaded.
+type xlsxWorkbook struct {
+	zip     *zip.ReadCloser
+	sheets  []xlsxSheet
+	strings []string
+}
+
+// xlsxSheet locates one worksheet inside the workbook archive.
+type xlsxSheet struct {
+	name   string
+	hidden bool
+	part   string
+}
+
+// xlsxRow is a worksheet row with its one-based row number and cell values indexed by column.
+type xlsxRow struct {
+	line  int
+	cells []string
+}
+
+// xlsxText is the rich-text form used by shared strings and inline strings.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+// text returns the plain text of t, joining rich-text runs.
+func (t xlsxText) text() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+// openXLSX opens the workbook at filePath and reads its sheet list and shared strings.
+func openXLSX(filePath string) (*xlsxWorkbook, error) {
+	r, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workbook: %w", err)
+	}
+	wb := &xlsxWorkbook{zip: r}
+	if err := wb.load(); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return wb, nil
+}
+
+// Close releases the workbook archive.
+func (wb *xlsxWorkbook) Close() error {
+	return wb.zip.Close()
+}
+
+// load reads the workbook's sheet list, their relationship targets and the shared string table.
+func (wb *xlsxWorkbook) load() error {
+	var workbook struct {
+		Sheets []struct {
+			Name  string `xml:"name,attr"`
+			State string `xml:"state,attr"`
+			ID    string `xml:"id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := wb.decode("xl/workbook.xml", &workbook); err != nil {
+		return err
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := wb.decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return err
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		if strings.HasPrefix(rel.Target, "/") {
+			targets[rel.ID] = strings.TrimPrefix(rel.Target, "/")
+		} else {
+			targets[rel.ID] = path.Join("xl", rel.Target)
+		}
+	}
+	for _, sheet := range workbook.Sheets {
+		part, ok := targets[sheet.ID]
+		if !ok {
+			return fmt.Errorf("workbook sheet %q has no worksheet part", sheet.Name)
+		}
+		wb.sheets = append(wb.sheets, xlsxSheet{name: sheet.Name, hidden: sheet.State != "" && sheet.State != "visible", part: part})
+	}
+
+	// Workbooks without any text cells have no shared string table.
+	if wb.file("xl/sharedStrings.xml") == nil {
+		return nil
+	}
+	var sst struct {
+		Items []xlsxText `xml:"si"`
+	}
+	if err := wb.decode("xl/sharedStrings.xml", &sst); err != nil {
+		return err
+	}
+	wb.strings = make([]string, len(sst.Items))
+	for i, item := range sst.Items {
+		wb.strings[i] = item.text()
+	}
+	return nil
+}
+
+// sheet returns the worksheet named name, ignoring case.
+func (wb *xlsxWorkbook) sheet(name string) (xlsxSheet, bool) {
+	for _, sheet := range wb.sheets {
+		if strings.EqualFold(sheet.name, name) {
+			return sheet, true
+		}
+	}
+	return xlsxSheet{}, false
+}
+
+// rows returns the non-empty rows of sheet in order, with numbers, booleans and strings rendered as text.
+func (wb *xlsxWorkbook) rows(sheet xlsxSheet) ([]xlsxRow, error) {
+	var worksheet struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				R      string   `xml:"r,attr"`
+				T      string   `xml:"t,attr"`
+				V      string   `xml:"v"`
+				Inline xlsxText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := wb.decode(sheet.part, &worksheet); err != nil {
+		return nil, err
+	}
+
+	rows := make([]xlsxRow, 0, len(worksheet.Rows))
+	line := 0
+	for _, row := range worksheet.Rows {
+		line++
+		if row.R > 0 {
+			line = row.R
+		}
+		var cells []string
+		column := -1
+		for _, cell := range row.Cells {
+			column++
+			if cell.R != "" {
+				if index, ok := xlsxColumnIndex(cell.R); ok {
+					column = index
+				}
+			}
+			value, err := wb.cellValue(cell.T, cell.V, cell.Inline)
+			if err != nil {
+				return nil, fmt.Errorf("sheet %q cell %s: %w", sheet.name, cell.R, err)
+			}
+			for len(cells) <= column {
+				cells = append(cells, "")
+			}
+			cells[column] = value
+		}
+		if !isBlankRecord(cells) {
+			rows = append(rows, xlsxRow{line: line, cells: cells})
+		}
+	}
+	return rows, nil
+}
+
+// cellValue returns the text of a cell of the given type.
+func (wb *xlsxWorkbook) cellValue(cellType, value string, inline xlsxText) (string, error) {
+	switch cellType {
+	case "s":
+		index, err := strconv.Atoi(value)
+		if err != nil || index < 0 || index >= len(wb.strings) {
+			return "", fmt.Errorf("invalid shared string index %q", value)
+		}
+		return wb.strings[index], nil
+	case "inlineStr":
+		return inline.text(), nil
+	case "b":
+		if value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case "", "n":
+		return formatXLSXNumber(value), nil
+	default:
+		return value, nil
+	}
+}
+
+// formatXLSXNumber renders a stored number the way Excel displays it, rounding to 15 significant digits
+// so binary floating-point noise such as 20.499999999999996 reads as 20.5.
+// Numbers too large to hold every digit, typically tracking numbers typed into a numeric column, keep Excel's
+// scientific notation so they are recognizably damaged rather than silently rounded.
+func formatXLSXNumber(value string) string {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(f, 'g', 15, 64), 64)
+	if err != nil {
+		return value
+	}
+	if math.Abs(rounded) >= 1e15 {
+		return strconv.FormatFloat(rounded, 'E', -1, 64)
+	}
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
+
+// xlsxColumnIndex returns the zero-based column of a cell reference such as "AB12".
+func xlsxColumnIndex(ref string) (int, bool) {
+	index := 0
+	letters := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A') + 1
+		letters++
+	}
+	return index - 1, letters > 0
+}
+
+// file returns the archive entry named name, or nil when the workbook has no such part.
+func (wb *xlsxWorkbook) file(name string) *zip.File {
+	for _, f := range wb.zip.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// decode unmarshals the XML part name into v.
+func (wb *xlsxWorkbook) decode(name string, v any) error {
+	f := wb.file(name)
+	if f == nil {
+		return fmt.Errorf("workbook is missing %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestXLSX writes a minimal workbook whose sheets are given as worksheet sheetData XML, in order.
+func writeTestXLSX(t *testing.T, sharedStrings []string, sheets [][2]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "shipments.xlsx")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create workbook: %v", err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+
+	var workbook, rels strings.Builder
+	workbook.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	rels.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	parts := map[string]string{}
+	for i, sheet := range sheets {
+		id := "rId" + string(rune('1'+i))
+		workbook.WriteString(`<sheet name="` + sheet[0] + `" sheetId="1" r:id="` + id + `"/>`)
+		rels.WriteString(`<Relationship Id="` + id + `" Target="worksheets/sheet` + string(rune('1'+i)) + `.xml"/>`)
+		parts["xl/worksheets/sheet"+string(rune('1'+i))+".xml"] = `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + sheet[1] + `</sheetData></worksheet>`
+	}
+	workbook.WriteString(`</sheets></workbook>`)
+	rels.WriteString(`</Relationships>`)
+	parts["xl/workbook.xml"] = workbook.String()
+	parts["xl/_rels/workbook.xml.rels"] = rels.String()
+
+	var sst strings.Builder
+	sst.WriteString(`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	for _, s := range sharedStrings {
+		sst.WriteString(`<si><t>` + s + `</t></si>`)
+	}
+	sst.WriteString(`</sst>`)
+	parts["xl/sharedStrings.xml"] = sst.String()
+
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to finish workbook: %v", err)
+	}
+	return path
+}
+
+func TestReadShipmentsXLSX(t *testing.T) {
+	headers := []string{"PO Numbers", "Master Tracking #", "Shipment Charges Applied Total", "Ship Carrier Name", "Recipient Customer ID", "Sale Source (UDF)", "Source Document Key", "Billing Type"}
+	shared := append(headers, "Daily Shipments", "ORDER1", "TRACK1", "UPS", "0090671", "BSC", "Prepaid")
+	headerRow := `<row r="3">`
+	for i, col := range []string{"A", "B", "C", "D", "E", "F", "G", "H"} {
+		headerRow += `<c r="` + col + `3" t="s"><v>` + string(rune('0'+i)) + `</v></c>`
+	}
+	headerRow += `</row>`
+	sheet := `<row r="1"><c r="A1" t="s"><v>8</v></c></row>` + headerRow +
+		// Shared strings, a numeric charge with floating-point noise, and a skipped Billing Type cell.
+		`<row r="4"><c r="A4" t="s"><v>9</v></c><c r="B4" t="s"><v>10</v></c><c r="C4"><v>20.499999999999996</v></c><c r="D4" t="s"><v>11</v></c><c r="E4" t="s"><v>12</v></c><c r="F4" t="s"><v>13</v></c></row>` +
+		`<row r="5"><c r="A5"/></row>` +
+		// Inline strings and an unknown sale source.
+		`<row r="6"><c r="A6" t="inlineStr"><is><t>ORDER2</t></is></c><c r="B6" t="inlineStr"><is><t>TRACK2</t></is></c><c r="C6"><v>3</v></c><c r="D6" t="s"><v>11</v></c><c r="E6" t="s"><v>12</v></c><c r="F6" t="inlineStr"><is><t>XYZ</t></is></c></row>`
+	path := writeTestXLSX(t, shared, [][2]string{{"Notes", `<row r="1"><c r="A1" t="s"><v>8</v></c></row>`}, {"Shipments", sheet}})
+
+	parsed, err := ReadShipmentsFile(path, DefaultShipmentCSVProfile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parsed.Shipments) != 1 {
+		t.Fatalf("shipments = %+v, want 1", parsed.Shipments)
+	}
+	got := parsed.Shipments[0]
+	if got.PONumber != "ORDER1" || got.TrackingCode != "TRACK1" || got.MakerCostCents != 2050 || got.Carrier != "UPS" || got.Line != 4 || got.BillingType != "" {
+		t.Errorf("shipment = %+v, want ORDER1/TRACK1 costing 2050 cents from line 4", got)
+	}
+	if len(parsed.Skipped) != 1 || parsed.Skipped[0].Kind != SkipUnknownSaleSource || parsed.Skipped[0].Line != 6 {
+		t.Errorf("skipped = %+v, want line 6 with an unknown sale source", parsed.Skipped)
+	}
+
+	profile := DefaultShipmentCSVProfile.clone()
+	profile.Sheet = "Notes"
+	if _, err := ReadShipmentsXLSX(path, profile); err == nil || !strings.Contains(err.Error(), "missing required headers") {
+		t.Errorf("ReadShipmentsXLSX() on the Notes sheet error = %v, want a missing headers error", err)
+	}
+	profile.Sheet = "Missing"
+	if _, err := ReadShipmentsXLSX(path, profile); err == nil || !strings.Contains(err.Error(), `no sheet named "Missing"`) {
+		t.Errorf("ReadShipmentsXLSX() on a missing sheet error = %v, want a missing sheet error", err)
+	}
+}
+
+func TestFormatXLSXNumber(t *testing.T) {
+	tests := map[string]string{
+		"20.499999999999996":  "20.5",
+		"19.99":               "19.99",
+		"1.5E+2":              "150",
+		"7":                   "7",
+		"9.4001118992234E+21": "9.4001118992234E+21",
+		"not a number":        "not a number",
+	}
+	for input, want := range tests {
+		if got := formatXLSXNumber(input); got != want {
+			t.Errorf("formatXLSXNumber(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,591 @@
+// Package cli implements the faire command-line subcommands, apart from the graphical interface so it can be tested
+// without a display.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	apppkg "github.com/Fepozopo/bsc-faire/internal/app"
+	"github.com/Fepozopo/bsc-faire/internal/version"
+	"github.com/joho/godotenv"
+)
+
+// CLI exit codes let scheduled jobs distinguish a clean run from partial and total failures.
+const (
+	exitOK             = 0
+	exitFailure        = 1
+	exitUsage          = 2
+	exitPartialFailure = 3
+)
+
+// DefaultShipmentWorkers is the number of concurrent shipment requests per sale source used by the GUI and CLI.
+const DefaultShipmentWorkers = 4
+
+const cliUsage = `Usage: faire <command> [flags]
+
+Commands:
+  ship process [flags] <file>      Add the shipments in a shipping-system CSV or XLSX export to their Faire orders
+  ship retry [flags] <saved.json>  Resubmit shipments saved by "ship process --save-failed"
+  orders list --sale-source SRC    Print every NEW or PROCESSING order
+  orders get --sale-source SRC ID  Print one order by display ID or bo_ ID
+  orders export --sale-source SRC  Export orders by --state or --ids to a CSV file
+  history [flags] [ID]             Print recorded shipment runs, optionally for one order or tracking code
+  version                          Print the application version
+
+Run "faire <command> <subcommand> -h" for the flags of a command.
+Running faire without arguments starts the graphical interface.
+
+Exit codes: 0 success, 1 failure, 2 usage error, 3 some shipments failed or rows were skipped.
+`
+
+// errUsage marks command-line errors that should print usage and exit with exitUsage.
+var errUsage = errors.New("usage error")
+
+// cli holds the output streams and cancellation context shared by every subcommand.
+type cli struct {
+	stdout io.Writer
+	stderr io.Writer
+	// ctx is canceled on interrupt so a shipment run stops sending and reports what completed.
+	ctx context.Context
+}
+
+// Run runs the subcommand in args and returns the process exit code.
+func Run(args []string, stdout, stderr io.Writer) int {
+	_ = godotenv.Load() // Flag defaults read FAIRE_USE_MOCK and FAIRE_MOCK_FAILS from .env as well as the environment.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	c := &cli{stdout: stdout, stderr: stderr, ctx: ctx}
+	code, err := c.run(args)
+	if err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintf(stderr, "%v\n\n%s", err, cliUsage)
+			return exitUsage
+		}
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitFailure
+	}
+	return code
+}
+
+// run dispatches args to the matching command group.
+func (c *cli) run(args []string) (int, error) {
+	if len(args) == 0 {
+		return exitUsage, fmt.Errorf("%w: missing command", errUsage)
+	}
+	switch args[0] {
+	case "ship":
+		if len(args) < 2 {
+			return exitUsage, fmt.Errorf("%w: expected \"ship process\" or \"ship retry\"", errUsage)
+		}
+		switch args[1] {
+		case "process":
+			return c.shipProcess(args[2:])
+		case "retry":
+			return c.shipRetry(args[2:])
+		}
+		return exitUsage, fmt.Errorf("%w: unknown ship command %q", errUsage, args[1])
+	case "orders":
+		if len(args) < 2 {
+			return exitUsage, fmt.Errorf("%w: expected \"orders list\", \"orders get\", or \"orders export\"", errUsage)
+		}
+		switch args[1] {
+		case "list":
+			return c.ordersList(args[2:])
+		case "get":
+			return c.ordersGet(args[2:])
+		case "export":
+			return c.ordersExport(args[2:])
+		}
+		return exitUsage, fmt.Errorf("%w: unknown orders command %q", errUsage, args[1])
+	case "history":
+		return c.history(args[1:])
+	case "version", "--version", "-version":
+		fmt.Fprintln(c.stdout, version.Version)
+		return exitOK, nil
+	case "help", "-h", "--help", "-help":
+		fmt.Fprint(c.stdout, cliUsage)
+		return exitOK, nil
+	}
+	return exitUsage, fmt.Errorf("%w: unknown command %q", errUsage, args[0])
+}
+
+// clientFlags are the flags shared by every command that talks to Faire.
+type clientFlags struct {
+	mock      bool
+	mockFails string
+}
+
+// register adds the client flags to fs, defaulting mock mode from FAIRE_USE_MOCK and FAIRE_MOCK_FAILS.
+func (f *clientFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.mock, "mock", envBool("FAIRE_USE_MOCK"), "use the mock Faire client instead of the live API")
+	fs.StringVar(&f.mockFails, "mock-fails", os.Getenv("FAIRE_MOCK_FAILS"), "comma-separated one-based mock shipment calls that fail")
+}
+
+// client returns the mock or live Faire client selected by the flags.
+func (f *clientFlags) client() (apppkg.FaireClientInterface, error) {
+	return NewFaireClient(f.mock, f.mockFails)
+}
+
+// token returns the API token for saleSource, or a placeholder token in mock mode.
+func (f *clientFlags) token(saleSource string) (string, error) {
+	if f.mock {
+		return "mock-token", nil
+	}
+	token, err := apppkg.GetToken(saleSource)
+	if err != nil || token == "" {
+		return "", fmt.Errorf("invalid or missing token for sale source %q", saleSource)
+	}
+	return token, nil
+}
+
+// shipProcess adds the shipments in one CSV or XLSX export and reports each outcome.
+func (c *cli) shipProcess(args []string) (int, error) {
+	fs := c.newFlagSet("ship process", "<file>")
+	var clientOptions clientFlags
+	clientOptions.register(fs)
+	asJSON := fs.Bool("json", false, "print the processed and failed shipments as JSON")
+	workers := fs.Int("workers", DefaultShipmentWorkers, "concurrent shipment requests per sale source")
+	quiet := fs.Bool("quiet", false, "do not print progress to stderr")
+	skipExisting := fs.Bool("skip-existing", true, "skip shipments whose tracking code is already on the Faire order")
+	orderState := fs.String("order-state", "block", "for canceled, delivered, or cancellation-requested orders: off, warn, or block")
+	profilePath := fs.String("profile", "", "JSON column-mapping profile for the export (default: FAIRE_SHIPMENT_PROFILE or the built-in profile)")
+	dryRun := fs.Bool("dry-run", false, "print the shipments that would be posted and skipped without posting them")
+	verifyOrders := fs.Bool("verify-orders", false, "with --dry-run, check each order's existence, state, and shipments with Faire")
+	saveFailed := fs.String("save-failed", "", "write failed shipments to this JSON file for \"ship retry\"")
+	resultsCSV := fs.String("results-csv", "", "write every shipment's outcome to this CSV file")
+	if err := fs.Parse(args); err != nil {
+		return flagError(err)
+	}
+	if fs.NArg() != 1 {
+		return exitUsage, fmt.Errorf("%w: ship process requires exactly one CSV or XLSX path", errUsage)
+	}
+
+	stateCheck, err := apppkg.ParseOrderStateCheck(*orderState)
+	if err != nil {
+		return exitUsage, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	profile, err := loadShipmentProfile(*profilePath)
+	if err != nil {
+		return exitFailure, err
+	}
+
+	client, err := clientOptions.client()
+	if err != nil {
+		return exitFailure, err
+	}
+
+	if *dryRun {
+		preview, err := apppkg.PreviewShipments(c.ctx, fs.Arg(0), client, apppkg.ShipmentPreviewOptions{
+			VerifyOrders:    *verifyOrders,
+			OrderStateCheck: stateCheck,
+			Profile:         &profile,
+		})
+		if err != nil {
+			return exitFailure, err
+		}
+		if *asJSON {
+			return exitOK, writeJSON(c.stdout, preview)
+		}
+		fmt.Fprint(c.stdout, apppkg.FormatShipmentPreview(preview))
+		return exitOK, nil
+	}
+
+	options := apppkg.ShipmentProcessOptions{Workers: *workers, SkipExistingShipments: *skipExisting, OrderStateCheck: stateCheck, Profile: &profile}
+	if !*quiet {
+		options.OnProgress = c.printProgress
+	}
+	startedAt := time.Now()
+	results, err := apppkg.ProcessShipmentsWithOptions(c.ctx, fs.Arg(0), client, options)
+	if historyErr := RecordShipmentRun(apppkg.NewShipmentRun(fs.Arg(0), clientOptions.mock, startedAt, results, err)); historyErr != nil {
+		fmt.Fprintf(c.stderr, "warning: %v\n", historyErr)
+	}
+	if err != nil {
+		return exitFailure, err
+	}
+	return c.reportShipmentResults(results, *asJSON, *saveFailed, *resultsCSV)
+}
+
+// shipRetry resubmits shipments saved with --save-failed without reading the original export.
+func (c *cli) shipRetry(args []string) (int, error) {
+	fs := c.newFlagSet("ship retry", "<saved.json>")
+	var clientOptions clientFlags
+	clientOptions.register(fs)
+	asJSON := fs.Bool("json", false, "print the processed and failed shipments as JSON")
+	workers := fs.Int("workers", DefaultShipmentWorkers, "concurrent shipment requests per sale source")
+	quiet := fs.Bool("quiet", false, "do not print progress to stderr")
+	skipExisting := fs.Bool("skip-existing", true, "skip shipments whose tracking code is already on the Faire order")
+	orderState := fs.String("order-state", "block", "for canceled, delivered, or cancellation-requested orders: off, warn, or block")
+	saveFailed := fs.String("save-failed", "", "write shipments that fail again to this JSON file")
+	resultsCSV := fs.String("results-csv", "", "write every shipment's outcome to this CSV file")
+	if err := fs.Parse(args); err != nil {
+		return flagError(err)
+	}
+	if fs.NArg() != 1 {
+		return exitUsage, fmt.Errorf("%w: ship retry requires exactly one saved shipments file", errUsage)
+	}
+
+	stateCheck, err := apppkg.ParseOrderStateCheck(*orderState)
+	if err != nil {
+		return exitUsage, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	payloads, err := apppkg.LoadShipmentPayloads(fs.Arg(0))
+	if err != nil {
+		return exitFailure, err
+	}
+	client, err := clientOptions.client()
+	if err != nil {
+		return exitFailure, err
+	}
+	options := apppkg.ShipmentProcessOptions{Workers: *workers, SkipExistingShipments: *skipExisting, OrderStateCheck: stateCheck}
+	if !*quiet {
+		options.OnProgress = c.printProgress
+	}
+	startedAt := time.Now()
+	results := apppkg.RetryShipments(c.ctx, payloads, client, options)
+	if historyErr := RecordShipmentRun(apppkg.NewShipmentRun(fs.Arg(0), clientOptions.mock, startedAt, results, nil)); historyErr != nil {
+		fmt.Fprintf(c.stderr, "warning: %v\n", historyErr)
+	}
+	return c.reportShipmentResults(results, *asJSON, *saveFailed, *resultsCSV)
+}
+
+// printProgress writes each completed shipment to stderr.
+func (c *cli) printProgress(p apppkg.ShipmentProgress) {
+	if p.Done > 0 {
+		fmt.Fprintf(c.stderr, "[%d/%d] %s\n", p.Done, p.Total, formatPayloadLine(p.Payload))
+	}
+}
+
+// reportShipmentResults prints results, saves the failures to saveFailed and every outcome to resultsCSV when they are set,
+// and returns the exit code.
+func (c *cli) reportShipmentResults(results apppkg.ShipmentResults, asJSON bool, saveFailed, resultsCSV string) (int, error) {
+	if resultsCSV != "" {
+		absPath, err := filepath.Abs(resultsCSV)
+		if err == nil {
+			err = apppkg.WriteShipmentResultsCSV(absPath, results)
+		}
+		if err != nil {
+			return exitFailure, err
+		}
+		fmt.Fprintf(c.stderr, "Wrote shipment results to %s\n", absPath)
+	}
+	if saveFailed != "" && len(results.Failed) > 0 {
+		if err := apppkg.SaveShipmentPayloads(saveFailed, results.Failed); err != nil {
+			return exitFailure, err
+		}
+		fmt.Fprintf(c.stderr, "Saved %d failed shipments to %s\n", len(results.Failed), saveFailed)
+	}
+
+	if asJSON {
+		if err := writeJSON(c.stdout, results); err != nil {
+			return exitFailure, err
+		}
+	} else {
+		fmt.Fprintln(c.stdout, FormatShipmentSummary(results))
+		for _, payload := range results.Failed {
+			fmt.Fprintf(c.stdout, "FAILED  %s\n", formatPayloadLine(payload))
+		}
+		for _, payload := range results.AlreadyPresent {
+			fmt.Fprintf(c.stdout, "PRESENT %s\n", formatPayloadLine(payload))
+		}
+		for _, payload := range results.Duplicates {
+			fmt.Fprintf(c.stdout, "DUPE    %s\n", formatPayloadLine(payload))
+		}
+		for _, payload := range results.Processed {
+			fmt.Fprintf(c.stdout, "OK      %s\n", formatPayloadLine(payload))
+		}
+		if len(results.SkippedRows) > 0 {
+			fmt.Fprintf(c.stdout, "Skipped rows:\n%s", apppkg.FormatSkippedRows(results.SkippedRows))
+		}
+		if len(results.Warnings) > 0 {
+			fmt.Fprintf(c.stdout, "Warnings:\n%s", apppkg.FormatShipmentWarnings(results.Warnings))
+		}
+	}
+
+	if len(results.Failed) > 0 || apppkg.CountUnexpectedSkips(results.SkippedRows) > 0 {
+		return exitPartialFailure, nil
+	}
+	return exitOK, nil
+}
+
+// history prints recorded shipment runs, optionally only those for an order or tracking code.
+func (c *cli) history(args []string) (int, error) {
+	fs := c.newFlagSet("history", "[order-or-tracking-code]")
+	asJSON := fs.Bool("json", false, "print the runs as JSON")
+	limit := fs.Int("limit", 20, "maximum number of runs to print, newest first (0 for all)")
+	if err := fs.Parse(args); err != nil {
+		return flagError(err)
+	}
+	if fs.NArg() > 1 {
+		return exitUsage, fmt.Errorf("%w: history accepts at most one order or tracking code", errUsage)
+	}
+
+	store, err := apppkg.DefaultShipmentHistory()
+	if err != nil {
+		return exitFailure, err
+	}
+	runs, skipped, err := store.Runs()
+	if err != nil {
+		return exitFailure, err
+	}
+	if skipped > 0 {
+		fmt.Fprintf(c.stderr, "warning: skipped %d unreadable lines in %s\n", skipped, store.Path)
+	}
+	runs = apppkg.SearchShipmentRuns(runs, fs.Arg(0))
+	if *limit > 0 && len(runs) > *limit {
+		runs = runs[:*limit]
+	}
+
+	if *asJSON {
+		return exitOK, writeJSON(c.stdout, runs)
+	}
+	fmt.Fprint(c.stdout, apppkg.FormatShipmentRuns(runs))
+	return exitOK, nil
+}
+
+// ordersList prints every active order for a sale source.
+func (c *cli) ordersList(args []string) (int, error) {
+	fs := c.newFlagSet("orders list", "")
+	var clientOptions clientFlags
+	clientOptions.register(fs)
+	saleSource := fs.String("sale-source", "", "sale source: 21, asc, bjp, bsc, gtg, oat, or sm")
+	asJSON := fs.Bool("json", false, "print the orders as JSON")
+	if err := fs.Parse(args); err != nil {
+		return flagError(err)
+	}
+	if *saleSource == "" || fs.NArg() != 0 {
+		return exitUsage, fmt.Errorf("%w: orders list requires --sale-source and no arguments", errUsage)
+	}
+
+	token, err := clientOptions.token(*saleSource)
+	if err != nil {
+		return exitFailure, err
+	}
+	client, err := clientOptions.client()
+	if err != nil {
+		return exitFailure, err
+	}
+	orders, err := apppkg.GetActiveOrders(c.ctx, client, token, nil)
+	if err != nil {
+		return exitFailure, err
+	}
+
+	if *asJSON {
+		return exitOK, writeJSON(c.stdout, orders)
+	}
+	fmt.Fprintln(c.stdout, apppkg.FormatOrders(orders))
+	return exitOK, nil
+}
+
+// ordersGet prints one order selected by display ID or Faire bo_ ID.
+func (c *cli) ordersGet(args []string) (int, error) {
+	fs := c.newFlagSet("orders get", "<order-id>")
+	var clientOptions clientFlags
+	clientOptions.register(fs)
+	saleSource := fs.String("sale-source", "", "sale source: 21, asc, bjp, bsc, gtg, oat, or sm")
+	asJSON := fs.Bool("json", false, "print the order as JSON")
+	if err := fs.Parse(args); err != nil {
+		return flagError(err)
+	}
+	if *saleSource == "" || fs.NArg() != 1 {
+		return exitUsage, fmt.Errorf("%w: orders get requires --sale-source and one order ID", errUsage)
+	}
+
+	token, err := clientOptions.token(*saleSource)
+	if err != nil {
+		return exitFailure, err
+	}
+	client, err := clientOptions.client()
+	if err != nil {
+		return exitFailure, err
+	}
+	response, err := client.GetOrderByID(c.ctx, fs.Arg(0), token)
+	if err != nil {
+		return exitFailure, fmt.Errorf("get order %q: %w", fs.Arg(0), err)
+	}
+	var order apppkg.Order
+	if err := json.Unmarshal(response, &order); err != nil {
+		return exitFailure, fmt.Errorf("parse order %q: %w", fs.Arg(0), err)
+	}
+
+	if *asJSON {
+		return exitOK, writeJSON(c.stdout, order)
+	}
+	fmt.Fprint(c.stdout, apppkg.FormatOrder(order))
+	return exitOK, nil
+}
+
+// ordersExport writes orders selected by state or identifier to a CSV file.
+func (c *cli) ordersExport(args []string) (int, error) {
+	fs := c.newFlagSet("orders export", "")
+	var clientOptions clientFlags
+	clientOptions.register(fs)
+	saleSource := fs.String("sale-source", "", "sale source: 21, asc, bjp, bsc, gtg, oat, or sm")
+	state := fs.String("state", "", "Faire order state to export, such as NEW or BACKORDERED")
+	ids := fs.String("ids", "", "comma-, semicolon-, or space-separated display IDs or bo_ IDs to export")
+	output := fs.String("output", "", "CSV output path (default: a faire_*_orders.csv file in Downloads)")
+	quiet := fs.Bool("quiet", false, "do not print progress to stderr")
+	if err := fs.Parse(args); err != nil {
+		return flagError(err)
+	}
+	if *saleSource == "" || fs.NArg() != 0 {
+		return exitUsage, fmt.Errorf("%w: orders export requires --sale-source and no arguments", errUsage)
+	}
+
+	filter := apppkg.OrderExportFilter{State: strings.ToUpper(strings.TrimSpace(*state))}
+	if *ids != "" {
+		filter.OrderIdentifiers = apppkg.ParseOrderIdentifiers(*ids)
+	}
+	if (filter.State == "") == (len(filter.OrderIdentifiers) == 0) {
+		return exitUsage, fmt.Errorf("%w: orders export requires exactly one of --state or --ids", errUsage)
+	}
+
+	outputPath, err := exportOutputPath(*output, filter)
+	if err != nil {
+		return exitFailure, err
+	}
+	token, err := clientOptions.token(*saleSource)
+	if err != nil {
+		return exitFailure, err
+	}
+
+	var onProgress func(apppkg.OrderProgress)
+	if !*quiet {
+		onProgress = func(p apppkg.OrderProgress) {
+			fmt.Fprintln(c.stderr, apppkg.FormatOrderProgress(p))
+		}
+	}
+	client, err := clientOptions.client()
+	if err != nil {
+		return exitFailure, err
+	}
+	count, err := apppkg.ExportOrdersToCSV(c.ctx, client, token, *saleSource, outputPath, filter, onProgress)
+	if err != nil {
+		return exitFailure, fmt.Errorf("export failed: %w", err)
+	}
+	fmt.Fprintf(c.stdout, "Exported %d orders to %s\n", count, outputPath)
+	return exitOK, nil
+}
+
+// flagError maps a flag parsing failure, which the flag package has already reported, to an exit code.
+func flagError(err error) (int, error) {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK, nil
+	}
+	return exitUsage, nil
+}
+
+// newFlagSet returns a flag set whose usage and errors are written to stderr.
+func (c *cli) newFlagSet(name, arguments string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: faire %s [flags] %s\n\nFlags:\n", name, arguments)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// exportOutputPath resolves --output against the working directory or chooses the GUI's Downloads filename.
+func exportOutputPath(output string, filter apppkg.OrderExportFilter) (string, error) {
+	if output != "" {
+		return filepath.Abs(output)
+	}
+	filename := "faire_selected_orders.csv"
+	if filter.State != "" {
+		filename = fmt.Sprintf("faire_%s_orders.csv", strings.ToLower(filter.State))
+	}
+	return apppkg.DownloadsFilePath(filename)
+}
+
+// RecordShipmentRun appends run to the local shipment history.
+func RecordShipmentRun(run apppkg.ShipmentRun) error {
+	store, err := apppkg.DefaultShipmentHistory()
+	if err != nil {
+		return err
+	}
+	return store.Record(run)
+}
+
+// loadShipmentProfile loads the profile at path, falling back to FAIRE_SHIPMENT_PROFILE and then the built-in profile.
+func loadShipmentProfile(path string) (apppkg.ShipmentCSVProfile, error) {
+	if path == "" {
+		return apppkg.ShipmentCSVProfileFromEnv()
+	}
+	return apppkg.LoadShipmentCSVProfile(path)
+}
+
+// NewFaireClient returns the mock client when useMock is set, failing the one-based shipment requests listed in mockFails.
+// The mock accepts any order ID so shipment CSVs can be rehearsed end to end. The live client fails when its
+// connection settings, such as FAIRE_PROXY_URL, are invalid.
+func NewFaireClient(useMock bool, mockFails string) (apppkg.FaireClientInterface, error) {
+	if !useMock {
+		return apppkg.NewFaireClient()
+	}
+	failMap := map[int]bool{}
+	for _, s := range strings.Split(mockFails, ",") {
+		if idx, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			failMap[idx] = true
+		}
+	}
+	return &apppkg.MockFaireClient{FailOnCall: failMap, Orders: apppkg.MockOrders, AutoCreateOrders: true}, nil
+}
+
+// FormatShipmentSummary returns the one-line count of shipment outcomes shown by the GUI and CLI.
+func FormatShipmentSummary(results apppkg.ShipmentResults) string {
+	total := len(results.Processed) + len(results.Failed) + len(results.AlreadyPresent) + len(results.Duplicates)
+	summary := fmt.Sprintf("Processed %d shipments: %d successful, %d failed, %d already present, %d duplicates, %d rows skipped",
+		total, len(results.Processed), len(results.Failed), len(results.AlreadyPresent), len(results.Duplicates),
+		apppkg.CountUnexpectedSkips(results.SkippedRows))
+	if results.Canceled {
+		summary = "Canceled. " + summary
+		for _, group := range apppkg.GroupFailures(results.Failed) {
+			switch group.Kind {
+			case apppkg.FailureNotSent:
+				summary += fmt.Sprintf("; %d failures were not sent", len(group.Payloads))
+			case apppkg.FailureOutcomeUnknown:
+				summary += fmt.Sprintf("; %d failures were in flight and may have been created, so check those orders before retrying", len(group.Payloads))
+			}
+		}
+	}
+	return summary
+}
+
+// formatPayloadLine returns a one-line summary of payload for terminal output.
+func formatPayloadLine(payload apppkg.ShipmentPayload) string {
+	line := fmt.Sprintf("%s sale_source=%s carrier=%s tracking=%s cost=$%.2f",
+		apppkg.OrderIDToDisplayID(payload.OrderID), payload.SaleSource, payload.Carrier, payload.TrackingCode, float64(payload.MakerCostCents)/100)
+	if payload.Created != nil && payload.Created.ID != "" {
+		line += " shipment_id=" + payload.Created.ID
+	}
+	if payload.ErrorMsg != "" {
+		line += " error=" + strconv.Quote(payload.ErrorMsg)
+	}
+	return line
+}
+
+// writeJSON writes value to w as indented JSON.
+func writeJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Errorf("write JSON: %w", err)
+	}
+	return nil
+}
+
+// envBool reports whether the environment variable name holds a true value such as 1 or true.
+func envBool(name string) bool {
+	value, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(name)))
+	return err == nil && value
+}
@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	apppkg "github.com/Fepozopo/bsc-faire/internal/app"
+	"github.com/Fepozopo/bsc-faire/internal/version"
+)
+
+// setupCLI isolates the CLI from the developer's configuration, recording history in a temporary directory.
+func setupCLI(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("FAIRE_HISTORY_PATH", filepath.Join(dir, "history.jsonl"))
+	t.Setenv("FAIRE_USE_MOCK", "")
+	t.Setenv("FAIRE_MOCK_FAILS", "")
+	t.Setenv("FAIRE_SHIPMENT_PROFILE", "")
+	t.Setenv("BSC_API_TOKEN", "dummy-token")
+	return dir
+}
+
+// runCommand runs the CLI with args and returns its exit code and output.
+func runCommand(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := Run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// writeShipmentsCSV writes content to a shipments.csv in dir and returns its path.
+func writeShipmentsCSV(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "shipments.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+	return path
+}
+
+func TestRunDispatchesCommands(t *testing.T) {
+	setupCLI(t)
+	tests := []struct {
+		args       []string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{args: nil, wantCode: exitUsage, wantStderr: "missing command"},
+		{args: []string{"version"}, wantCode: exitOK, wantStdout: version.Version},
+		{args: []string{"help"}, wantCode: exitOK, wantStdout: "Usage: faire <command>"},
+		{args: []string{"bogus"}, wantCode: exitUsage, wantStderr: `unknown command "bogus"`},
+		{args: []string{"ship"}, wantCode: exitUsage, wantStderr: `expected "ship process" or "ship retry"`},
+		{args: []string{"ship", "bogus"}, wantCode: exitUsage, wantStderr: `unknown ship command "bogus"`},
+		{args: []string{"orders", "bogus"}, wantCode: exitUsage, wantStderr: `unknown orders command "bogus"`},
+		{args: []string{"orders", "get", "--mock", "--sale-source", "bsc", "MOCK-ORDER-2"}, wantCode: exitOK, wantStdout: "MOCK-ORDER-2"},
+		{args: []string{"history"}, wantCode: exitOK, wantStdout: "No recorded shipment runs."},
+	}
+	for _, tt := range tests {
+		code, stdout, stderr := runCommand(tt.args...)
+		if code != tt.wantCode || !strings.Contains(stdout, tt.wantStdout) || !strings.Contains(stderr, tt.wantStderr) {
+			t.Errorf("Run(%q) = %d, stdout %q, stderr %q; want %d with %q on stdout and %q on stderr",
+				tt.args, code, stdout, stderr, tt.wantCode, tt.wantStdout, tt.wantStderr)
+		}
+	}
+}
+
+func TestRunRejectsBadFlags(t *testing.T) {
+	setupCLI(t)
+	tests := []struct {
+		args       []string
+		wantCode   int
+		wantStderr string
+	}{
+		{args: []string{"ship", "process", "--bogus", "shipments.csv"}, wantCode: exitUsage, wantStderr: "flag provided but not defined: -bogus"},
+		{args: []string{"ship", "process", "-h"}, wantCode: exitOK, wantStderr: "Usage: faire ship process [flags] <file>"},
+		{args: []string{"ship", "process", "--mock"}, wantCode: exitUsage, wantStderr: "requires exactly one CSV or XLSX path"},
+		{args: []string{"ship", "process", "--order-state", "sometimes", "shipments.csv"}, wantCode: exitUsage, wantStderr: "sometimes"},
+		{args: []string{"ship", "retry", "a.json", "b.json"}, wantCode: exitUsage, wantStderr: "requires exactly one saved shipments file"},
+		{args: []string{"orders", "list", "--mock"}, wantCode: exitUsage, wantStderr: "requires --sale-source"},
+		{args: []string{"orders", "export", "--mock", "--sale-source", "bsc"}, wantCode: exitUsage, wantStderr: "exactly one of --state or --ids"},
+		{args: []string{"history", "A", "B"}, wantCode: exitUsage, wantStderr: "at most one order or tracking code"},
+	}
+	for _, tt := range tests {
+		code, _, stderr := runCommand(tt.args...)
+		if code != tt.wantCode || !strings.Contains(stderr, tt.wantStderr) {
+			t.Errorf("Run(%q) = %d, stderr %q; want %d with %q", tt.args, code, stderr, tt.wantCode, tt.wantStderr)
+		}
+	}
+}
+
+func TestRunShipProcessExitCodes(t *testing.T) {
+	dir := setupCLI(t)
+	path := writeShipmentsCSV(t, dir, `Source Document Key,PO Numbers,Master Tracking #,Shipment Charges Applied Total,Ship Carrier Name,Billing Type,Recipient Customer ID,Sale Source (UDF)
+DOC1,ORDER1,1Z999AA10123456784,1.00,UPS,Prepaid,0090671,BSC
+DOC2,ORDER2,1Z999AA10123456785,2.00,UPS,Prepaid,0090671,BSC
+`)
+
+	code, stdout, stderr := runCommand("ship", "process", "--mock", "--quiet", path)
+	if code != exitOK || !strings.Contains(stdout, "Processed 2 shipments: 2 successful, 0 failed") || strings.Count(stdout, "OK      ") != 2 {
+		t.Errorf("clean run = %d, stdout %q, stderr %q; want exit 0 with both shipments OK", code, stdout, stderr)
+	}
+
+	// The mock keeps no state between runs, so the same rows are posted again and the first request fails.
+	failedPath := filepath.Join(dir, "failed.json")
+	code, stdout, stderr = runCommand("ship", "process", "--mock", "--mock-fails", "1", "--save-failed", failedPath, path)
+	if code != exitPartialFailure || !strings.Contains(stdout, "1 successful, 1 failed") || !strings.Contains(stdout, "FAILED  ORDER1") {
+		t.Errorf("partial failure = %d, stdout %q; want exit 3 with ORDER1 failed", code, stdout)
+	}
+	if !strings.Contains(stderr, "[1/2]") || !strings.Contains(stderr, "Saved 1 failed shipments to "+failedPath) {
+		t.Errorf("partial failure stderr = %q, want progress and the saved failures", stderr)
+	}
+
+	code, stdout, _ = runCommand("ship", "retry", "--mock", "--quiet", "--json", failedPath)
+	var results apppkg.ShipmentResults
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("retry output is not JSON: %v\n%s", err, stdout)
+	}
+	if code != exitOK || len(results.Processed) != 1 || results.Processed[0].OrderID != "bo_order1" {
+		t.Errorf("retry = %d, processed %+v; want exit 0 with ORDER1 processed", code, results.Processed)
+	}
+
+	code, _, stderr = runCommand("ship", "process", "--mock", filepath.Join(dir, "missing.csv"))
+	if code != exitFailure || !strings.HasPrefix(stderr, "error: ") {
+		t.Errorf("missing file = %d, stderr %q; want exit 1 with an error", code, stderr)
+	}
+
+	code, stdout, _ = runCommand("history", "--limit", "0", "ORDER1")
+	if code != exitOK || strings.Count(stdout, "ORDER1") != 3 {
+		t.Errorf("history = %d, stdout %q; want the three runs that sent ORDER1", code, stdout)
+	}
+}